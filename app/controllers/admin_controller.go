@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"encoding/json"
+	"insider-case/app/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type IAdminController interface {
+	RebuildStats(w http.ResponseWriter, r *http.Request)
+	CheckStats(w http.ResponseWriter, r *http.Request)
+}
+
+type AdminController struct {
+	statsService services.IStatsService
+}
+
+func NewAdminController(statsService services.IStatsService) *AdminController {
+	return &AdminController{statsService: statsService}
+}
+
+func (ac *AdminController) RebuildStats(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := ac.statsService.RebuildStats(uint(leagueID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (ac *AdminController) CheckStats(w http.ResponseWriter, r *http.Request) {
+	reports, err := ac.statsService.CheckStats()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"insider-case/app/dto"
+	"insider-case/app/services"
+
+	"github.com/gorilla/mux"
+)
+
+type CupController struct {
+	service services.ICupService
+}
+
+func NewCupController(service services.ICupService) *CupController {
+	return &CupController{service: service}
+}
+
+func (cc *CupController) CreateCup(w http.ResponseWriter, r *http.Request) {
+	var req dto.CupCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	bracket, err := cc.service.CreateCup(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bracket)
+}
+
+func (cc *CupController) GetBracket(w http.ResponseWriter, r *http.Request) {
+	cupID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid cup ID", http.StatusBadRequest)
+		return
+	}
+
+	bracket, err := cc.service.GetBracket(uint(cupID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bracket)
+}
+
+func (cc *CupController) SimulateRound(w http.ResponseWriter, r *http.Request) {
+	cupID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid cup ID", http.StatusBadRequest)
+		return
+	}
+
+	round, err := cc.service.SimulateRound(uint(cupID))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(round)
+}
+
+func (cc *CupController) GetRoundProbabilities(w http.ResponseWriter, r *http.Request) {
+	cupID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid cup ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.EstimationRequest
+	if iterationsStr := r.URL.Query().Get("iterations"); iterationsStr != "" {
+		if req.Iterations, err = strconv.Atoi(iterationsStr); err != nil {
+			http.Error(w, "Invalid iteration count", http.StatusBadRequest)
+			return
+		}
+	}
+	if stdErrStr := r.URL.Query().Get("std_err"); stdErrStr != "" {
+		if req.StdErr, err = strconv.ParseFloat(stdErrStr, 64); err != nil {
+			http.Error(w, "Invalid standard error", http.StatusBadRequest)
+			return
+		}
+	}
+
+	projection, err := cc.service.GetRoundProbabilities(uint(cupID), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projection)
+}
@@ -2,11 +2,17 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
 	"insider-case/app/dto"
+	"insider-case/app/helpers"
+	"insider-case/app/repository"
 	"insider-case/app/services"
+
+	"github.com/gorilla/mux"
 )
 
 type LeagueController struct {
@@ -37,6 +43,7 @@ func (lc *LeagueController) CreateLeague(w http.ResponseWriter, r *http.Request)
 func (lc *LeagueController) SimulateWeek(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		LeagueID uint `json:"leagueID"`
+		Week     *int `json:"week"` // week the client expects to simulate, optional
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -44,9 +51,9 @@ func (lc *LeagueController) SimulateWeek(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	week, err := lc.service.SimulateWeek(req.LeagueID)
+	week, err := lc.service.SimulateWeek(req.LeagueID, req.Week)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -66,7 +73,7 @@ func (lc *LeagueController) PlayRemainingMatches(w http.ResponseWriter, r *http.
 
 	weeks, err := lc.service.PlayRemainingMatches(req.LeagueID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -80,10 +87,14 @@ func (lc *LeagueController) UserPlayWeek(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if len(req) == 0 {
+		http.Error(w, "at least one match result is required", http.StatusBadRequest)
+		return
+	}
 
 	match, err := lc.service.UserPlayWeek(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
 		return
 	}
 
@@ -104,7 +115,21 @@ func (lc *LeagueController) GetChampionshipEstimations(w http.ResponseWriter, r
 		return
 	}
 
-	estimations, err := lc.service.GetChampionshipEstimationByLeagueID(uint(leagueID))
+	var req dto.EstimationRequest
+	if iterationsStr := r.URL.Query().Get("iterations"); iterationsStr != "" {
+		if req.Iterations, err = strconv.Atoi(iterationsStr); err != nil {
+			http.Error(w, "Invalid iteration count", http.StatusBadRequest)
+			return
+		}
+	}
+	if stdErrStr := r.URL.Query().Get("std_err"); stdErrStr != "" {
+		if req.StdErr, err = strconv.ParseFloat(stdErrStr, 64); err != nil {
+			http.Error(w, "Invalid standard error", http.StatusBadRequest)
+			return
+		}
+	}
+
+	estimations, err := lc.service.GetChampionshipEstimationByLeagueID(uint(leagueID), req)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -113,3 +138,195 @@ func (lc *LeagueController) GetChampionshipEstimations(w http.ResponseWriter, r
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(estimations)
 }
+
+func (lc *LeagueController) GetProjections(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	var week *int
+	if weekStr := r.URL.Query().Get("week"); weekStr != "" {
+		weekNumber, err := strconv.Atoi(weekStr)
+		if err != nil {
+			http.Error(w, "Invalid week number", http.StatusBadRequest)
+			return
+		}
+		week = &weekNumber
+	}
+
+	projection, err := lc.service.GetProjections(uint(leagueID), week)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projection)
+}
+
+func (lc *LeagueController) GetStandings(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	standings, err := lc.service.GetStandings(uint(leagueID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(standings)
+}
+
+func (lc *LeagueController) NextSeason(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	// The body is optional, an empty one keeps every strength
+	var req dto.NextSeasonRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := lc.service.NextSeason(uint(leagueID), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (lc *LeagueController) GetAllTimeTable(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	table, err := lc.service.GetAllTimeTable(uint(leagueID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(table)
+}
+
+func (lc *LeagueController) RewindLeague(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+	week, err := strconv.Atoi(r.URL.Query().Get("week"))
+	if err != nil {
+		http.Error(w, "Invalid week number", http.StatusBadRequest)
+		return
+	}
+	req := dto.RewindRequest{Week: week}
+	if seedStr := r.URL.Query().Get("seed"); seedStr != "" {
+		seed, err := strconv.ParseInt(seedStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid seed", http.StatusBadRequest)
+			return
+		}
+		req.Seed = &seed
+	}
+	if reseedStr := r.URL.Query().Get("reseed"); reseedStr != "" {
+		if req.Reseed, err = strconv.ParseBool(reseedStr); err != nil {
+			http.Error(w, "Invalid reseed flag", http.StatusBadRequest)
+			return
+		}
+	}
+
+	standings, err := lc.service.RewindLeague(uint(leagueID), req)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(standings)
+}
+
+func (lc *LeagueController) UndoWeek(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	standings, err := lc.service.UndoWeek(uint(leagueID))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(standings)
+}
+
+func (lc *LeagueController) EditMatchResult(w http.ResponseWriter, r *http.Request) {
+	matchID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid match ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.MatchResultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	match, err := lc.service.EditMatchResult(uint(matchID), req)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(match)
+}
+
+func (lc *LeagueController) GetAuditTrail(w http.ResponseWriter, r *http.Request) {
+	leagueID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid league ID", http.StatusBadRequest)
+		return
+	}
+
+	audits, err := lc.service.GetAuditTrail(uint(leagueID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(audits)
+}
+
+// errorStatus answers 409 Conflict when the request lost a race against another request changing
+// the same league, 400 Bad Request for invalid input and the given status otherwise
+func errorStatus(err error, status int) int {
+	if errors.Is(err, repository.ErrLeagueConflict) {
+		return http.StatusConflict
+	}
+	var validationErr *helpers.ValidationError
+	if errors.As(err, &validationErr) {
+		return http.StatusBadRequest
+	}
+	return status
+}
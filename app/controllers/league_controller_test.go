@@ -0,0 +1,130 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"insider-case/app/database"
+	"insider-case/app/dto"
+	"insider-case/app/models"
+	"insider-case/app/routes"
+	"insider-case/config"
+
+	"github.com/gorilla/mux"
+)
+
+// newTestServer serves the api against the database of the environment, skipping the test
+// when no database is configured
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	// the migrations are globbed relative to the repository root
+	t.Chdir("../..")
+
+	cfg, err := config.LoadConfig()
+	if err != nil || cfg.DB.Host == "" {
+		t.Skip("no database configured, set DB_HOST to run this test")
+	}
+	database.Connect(cfg)
+	database.MigrateAll()
+
+	r := mux.NewRouter()
+	routes.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func postJSON(url string, body any) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return http.Post(url, "application/json", bytes.NewReader(payload))
+}
+
+// TestSimulateWeekConcurrent races requests for the same week, only one of them may play it
+func TestSimulateWeekConcurrent(t *testing.T) {
+	srv := newTestServer(t)
+
+	resp, err := postJSON(srv.URL+"/api/leagues", dto.LeagueCreateRequest{
+		Name:      "Concurrent Simulate Week",
+		TeamCount: 4,
+		Teams: []dto.TeamRequest{
+			{Name: "Fenerbahçe", Strength: 2000},
+			{Name: "Beşiktaş", Strength: 2100},
+			{Name: "Galatasaray", Strength: 1800},
+			{Name: "Trabzonspor", Strength: 1700},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create league: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to create league: status %d", resp.StatusCode)
+	}
+	var league dto.LeagueResponse
+	if err := json.NewDecoder(resp.Body).Decode(&league); err != nil {
+		t.Fatalf("failed to decode league: %v", err)
+	}
+	t.Cleanup(func() {
+		database.GetDB().Delete(&models.League{}, league.ID)
+	})
+
+	const requests = 8
+	week := league.CurrWeek
+	start := make(chan struct{})
+	statuses := make(chan int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			resp, err := postJSON(srv.URL+"/api/leagues/simulate-week", map[string]any{
+				"leagueID": league.ID,
+				"week":     week,
+			})
+			if err != nil {
+				t.Errorf("failed to simulate week: %v", err)
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(statuses)
+
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != requests-1 {
+		t.Fatalf("expected 1 OK and %d conflicts, got %v", requests-1, counts)
+	}
+
+	db := database.GetDB()
+	var stored models.League
+	if err := db.First(&stored, league.ID).Error; err != nil {
+		t.Fatalf("failed to get league: %v", err)
+	}
+	if stored.CurrWeek != week+1 {
+		t.Errorf("expected the league at week %d, got week %d", week+1, stored.CurrWeek)
+	}
+
+	var matches []models.Match
+	if err := db.Where("league_id = ?", league.ID).Find(&matches).Error; err != nil {
+		t.Fatalf("failed to get matches: %v", err)
+	}
+	for _, match := range matches {
+		if match.Played != (match.Week == week) {
+			t.Errorf("match %d of week %d: played %t", match.ID, match.Week, match.Played)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"insider-case/app/dto"
+	"insider-case/app/services"
+
+	"github.com/gorilla/mux"
+)
+
+type PyramidController struct {
+	service services.IPyramidService
+}
+
+func NewPyramidController(service services.IPyramidService) *PyramidController {
+	return &PyramidController{service: service}
+}
+
+func (pc *PyramidController) CreatePyramid(w http.ResponseWriter, r *http.Request) {
+	var req dto.PyramidCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	pyramid, err := pc.service.CreatePyramid(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pyramid)
+}
+
+func (pc *PyramidController) GetPyramid(w http.ResponseWriter, r *http.Request) {
+	pyramidID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid pyramid ID", http.StatusBadRequest)
+		return
+	}
+
+	pyramid, err := pc.service.GetPyramid(uint(pyramidID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pyramid)
+}
+
+func (pc *PyramidController) SimulateWeek(w http.ResponseWriter, r *http.Request) {
+	pyramidID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid pyramid ID", http.StatusBadRequest)
+		return
+	}
+
+	pyramid, err := pc.service.SimulateWeek(uint(pyramidID))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pyramid)
+}
+
+func (pc *PyramidController) SimulateSeason(w http.ResponseWriter, r *http.Request) {
+	pyramidID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid pyramid ID", http.StatusBadRequest)
+		return
+	}
+
+	pyramid, err := pc.service.SimulateSeason(uint(pyramidID))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pyramid)
+}
+
+func (pc *PyramidController) NextSeason(w http.ResponseWriter, r *http.Request) {
+	pyramidID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid pyramid ID", http.StatusBadRequest)
+		return
+	}
+
+	pyramid, err := pc.service.NextSeason(uint(pyramidID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pyramid)
+}
+
+func (pc *PyramidController) GetProbabilities(w http.ResponseWriter, r *http.Request) {
+	pyramidID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid pyramid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.EstimationRequest
+	if iterationsStr := r.URL.Query().Get("iterations"); iterationsStr != "" {
+		if req.Iterations, err = strconv.Atoi(iterationsStr); err != nil {
+			http.Error(w, "Invalid iteration count", http.StatusBadRequest)
+			return
+		}
+	}
+	if stdErrStr := r.URL.Query().Get("std_err"); stdErrStr != "" {
+		if req.StdErr, err = strconv.ParseFloat(stdErrStr, 64); err != nil {
+			http.Error(w, "Invalid standard error", http.StatusBadRequest)
+			return
+		}
+	}
+
+	projection, err := pc.service.GetProbabilities(uint(pyramidID), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projection)
+}
@@ -11,6 +11,7 @@ import (
 
 type ITeamController interface {
 	GetTeamsByLeagueID(w http.ResponseWriter, r *http.Request)
+	GetTeamRatings(w http.ResponseWriter, r *http.Request)
 }
 type TeamController struct {
 	service services.ITeamService
@@ -36,3 +37,21 @@ func (tc *TeamController) GetTeamsByLeagueID(w http.ResponseWriter, r *http.Requ
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(teams)
 }
+
+func (tc *TeamController) GetTeamRatings(w http.ResponseWriter, r *http.Request) {
+	teamIDStr := mux.Vars(r)["teamID"]
+	teamID, err := strconv.ParseUint(teamIDStr, 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid team ID", http.StatusBadRequest)
+		return
+	}
+
+	ratings, err := tc.service.GetTeamRatings(uint(teamID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ratings)
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"insider-case/app/dto"
+	"insider-case/app/services"
+
+	"github.com/gorilla/mux"
+)
+
+type TournamentController struct {
+	service services.ITournamentService
+}
+
+func NewTournamentController(service services.ITournamentService) *TournamentController {
+	return &TournamentController{service: service}
+}
+
+func (tc *TournamentController) CreateTournament(w http.ResponseWriter, r *http.Request) {
+	var req dto.TournamentCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tournament, err := tc.service.CreateTournament(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tournament)
+}
+
+func (tc *TournamentController) GetTournament(w http.ResponseWriter, r *http.Request) {
+	tournamentID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tournament ID", http.StatusBadRequest)
+		return
+	}
+
+	tournament, err := tc.service.GetTournament(uint(tournamentID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tournament)
+}
+
+func (tc *TournamentController) SimulateRound(w http.ResponseWriter, r *http.Request) {
+	tournamentID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tournament ID", http.StatusBadRequest)
+		return
+	}
+
+	tournament, err := tc.service.SimulateRound(uint(tournamentID))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tournament)
+}
+
+func (tc *TournamentController) SimulateAll(w http.ResponseWriter, r *http.Request) {
+	tournamentID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tournament ID", http.StatusBadRequest)
+		return
+	}
+
+	tournament, err := tc.service.SimulateAll(uint(tournamentID))
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tournament)
+}
+
+func (tc *TournamentController) GetProbabilities(w http.ResponseWriter, r *http.Request) {
+	tournamentID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid tournament ID", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.EstimationRequest
+	if iterationsStr := r.URL.Query().Get("iterations"); iterationsStr != "" {
+		if req.Iterations, err = strconv.Atoi(iterationsStr); err != nil {
+			http.Error(w, "Invalid iteration count", http.StatusBadRequest)
+			return
+		}
+	}
+	if stdErrStr := r.URL.Query().Get("std_err"); stdErrStr != "" {
+		if req.StdErr, err = strconv.ParseFloat(stdErrStr, 64); err != nil {
+			http.Error(w, "Invalid standard error", http.StatusBadRequest)
+			return
+		}
+	}
+
+	projection, err := tc.service.GetProbabilities(uint(tournamentID), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projection)
+}
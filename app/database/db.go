@@ -5,6 +5,8 @@ import (
 	"insider-case/config"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -63,8 +65,18 @@ func ExecuteSQLFile(path string) error {
 	return nil
 }
 
+// MigrateAll executes every migration file in lexical order. Migrations are
+// written to be idempotent so they can be re-run on every start.
 func MigrateAll() {
-	if err := ExecuteSQLFile("app/database/migrations/001_create_tables.sql"); err != nil {
-		log.Fatal("Failed to execute SQL migrations: ", err)
+	files, err := filepath.Glob("app/database/migrations/*.sql")
+	if err != nil {
+		log.Fatal("Failed to list SQL migrations: ", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		if err := ExecuteSQLFile(file); err != nil {
+			log.Fatal("Failed to execute SQL migrations: ", err)
+		}
 	}
 }
@@ -0,0 +1,3 @@
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS engine VARCHAR(50) NOT NULL DEFAULT 'classic';
+
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS events JSONB;
@@ -0,0 +1,4 @@
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS base_goals REAL NOT NULL DEFAULT 1.3;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS home_advantage REAL NOT NULL DEFAULT 1.2;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS strength_weight REAL NOT NULL DEFAULT 1.0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS form_weight REAL NOT NULL DEFAULT 0.2;
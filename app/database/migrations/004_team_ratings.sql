@@ -0,0 +1,36 @@
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS use_rating BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS rating REAL;
+UPDATE teams SET rating = strength WHERE rating IS NULL;
+
+CREATE TABLE IF NOT EXISTS team_ratings (
+    id SERIAL PRIMARY KEY,
+    team_id INTEGER NOT NULL,
+    league_id INTEGER NOT NULL,
+    week INTEGER NOT NULL,
+    match_id INTEGER,
+    rating REAL NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_team_ratings_team'
+    ) THEN
+        ALTER TABLE team_ratings
+        ADD CONSTRAINT fk_team_ratings_team
+        FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_team_ratings_match'
+    ) THEN
+        ALTER TABLE team_ratings
+        ADD CONSTRAINT fk_team_ratings_match
+        FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE CASCADE;
+    END IF;
+END $$;
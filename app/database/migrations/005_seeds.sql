@@ -0,0 +1,3 @@
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS seed BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS seed BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS simulation_iterations INTEGER NOT NULL DEFAULT 10000;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS target_std_err REAL NOT NULL DEFAULT 0;
+
+ALTER TABLE team_stats ADD COLUMN IF NOT EXISTS estimation_iterations INTEGER DEFAULT 0;
+ALTER TABLE team_stats ADD COLUMN IF NOT EXISTS estimation_std_err REAL DEFAULT 0.0;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS projections (
+    id SERIAL PRIMARY KEY,
+    league_id INTEGER NOT NULL,
+    week INTEGER NOT NULL,
+    iterations INTEGER NOT NULL,
+    projection_json JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_projections_league'
+    ) THEN
+        ALTER TABLE projections
+        ADD CONSTRAINT fk_projections_league
+        FOREIGN KEY (league_id) REFERENCES leagues(id) ON DELETE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,2 @@
+-- Existing leagues keep publishing estimations from week 4 on
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS estimation_start_week INTEGER NOT NULL DEFAULT 4;
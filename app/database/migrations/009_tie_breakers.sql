@@ -0,0 +1,26 @@
+-- Existing leagues keep ranking by goal difference and goals scored
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS tie_breakers JSONB NOT NULL DEFAULT '["goal_difference", "goals_for"]';
+
+-- Backfill the away goals of the matches already played, only when the column is added so
+-- the stats are not recomputed on every start
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'team_stats' AND column_name = 'away_goals_for'
+    ) THEN
+        ALTER TABLE team_stats ADD COLUMN away_goals_for INTEGER NOT NULL DEFAULT 0;
+
+        UPDATE team_stats ts
+        SET away_goals_for = played.goals
+        FROM (
+            SELECT away_team_id, SUM(away_score) AS goals
+            FROM matches
+            WHERE played
+            GROUP BY away_team_id
+        ) played
+        WHERE ts.team_id = played.away_team_id;
+    END IF;
+END $$;
+
+ALTER TABLE team_stats ADD COLUMN IF NOT EXISTS fair_play_points INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,14 @@
+-- Existing leagues keep the 3/1/0 points system
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_win INTEGER NOT NULL DEFAULT 3;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_draw INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_loss INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_goals_bonus_threshold INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_goals_bonus INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_losing_bonus_margin INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_losing_bonus INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_no_draws BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_shootout_win INTEGER NOT NULL DEFAULT 2;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS points_shootout_loss INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS home_penalties INTEGER;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS away_penalties INTEGER;
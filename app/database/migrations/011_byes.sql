@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS byes (
+    id SERIAL PRIMARY KEY,
+    league_id INTEGER NOT NULL,
+    week INTEGER NOT NULL,
+    team_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_byes_league'
+    ) THEN
+        ALTER TABLE byes
+        ADD CONSTRAINT fk_byes_league
+        FOREIGN KEY (league_id) REFERENCES leagues(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_byes_team'
+    ) THEN
+        ALTER TABLE byes
+        ADD CONSTRAINT fk_byes_team
+        FOREIGN KEY (team_id) REFERENCES teams(id) ON DELETE CASCADE;
+    END IF;
+END $$;
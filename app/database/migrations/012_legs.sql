@@ -0,0 +1,2 @@
+-- Existing leagues are double round robins
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS legs INTEGER NOT NULL DEFAULT 2;
@@ -0,0 +1,48 @@
+-- Existing competitions are leagues
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS format VARCHAR(16) NOT NULL DEFAULT 'league';
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS away_goals BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS seeded BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS ties (
+    id SERIAL PRIMARY KEY,
+    league_id INTEGER NOT NULL,
+    stage VARCHAR(16) NOT NULL,
+    round INTEGER NOT NULL,
+    slot INTEGER NOT NULL,
+    home_team_id INTEGER,
+    away_team_id INTEGER,
+    winner_id INTEGER,
+    decided_by VARCHAR(16) NOT NULL DEFAULT '',
+    home_aggregate INTEGER NOT NULL DEFAULT 0,
+    away_aggregate INTEGER NOT NULL DEFAULT 0,
+    home_penalties INTEGER,
+    away_penalties INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_ties_league'
+    ) THEN
+        ALTER TABLE ties
+        ADD CONSTRAINT fk_ties_league
+        FOREIGN KEY (league_id) REFERENCES leagues(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Legs of knockout ties, league matches have no tie and leg 0
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS tie_id INTEGER;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS leg INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS extra_time BOOLEAN NOT NULL DEFAULT FALSE;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_matches_tie'
+    ) THEN
+        ALTER TABLE matches
+        ADD CONSTRAINT fk_matches_tie
+        FOREIGN KEY (tie_id) REFERENCES ties(id) ON DELETE CASCADE;
+    END IF;
+END $$;
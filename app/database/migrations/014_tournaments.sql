@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS tournaments (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    group_count INTEGER NOT NULL,
+    qualifiers INTEGER NOT NULL,
+    seeded BOOLEAN NOT NULL DEFAULT FALSE,
+    seed BIGINT NOT NULL DEFAULT 0,
+    knockout_legs INTEGER NOT NULL DEFAULT 1,
+    away_goals BOOLEAN NOT NULL DEFAULT FALSE,
+    knockout_id INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Groups of a tournament are leagues
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS tournament_id INTEGER;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_leagues_tournament'
+    ) THEN
+        ALTER TABLE leagues
+        ADD CONSTRAINT fk_leagues_tournament
+        FOREIGN KEY (tournament_id) REFERENCES tournaments(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_tournaments_knockout'
+    ) THEN
+        ALTER TABLE tournaments
+        ADD CONSTRAINT fk_tournaments_knockout
+        FOREIGN KEY (knockout_id) REFERENCES leagues(id) ON DELETE SET NULL;
+    END IF;
+END $$;
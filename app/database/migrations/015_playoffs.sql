@@ -0,0 +1,6 @@
+-- Existing leagues award the title to the top team and play no playoffs
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS playoff_teams INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS playoff_relegation_from INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS playoff_relegation_to INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS playoff_legs INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS playoff_away_goals BOOLEAN NOT NULL DEFAULT FALSE;
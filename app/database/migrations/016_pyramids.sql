@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS pyramids (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    swaps INTEGER NOT NULL DEFAULT 1,
+    season INTEGER NOT NULL DEFAULT 1,
+    seed BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Divisions of a pyramid are leagues, one per division and season
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS pyramid_id INTEGER;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS season INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS division INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS promoted INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS relegated INTEGER NOT NULL DEFAULT 0;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_leagues_pyramid'
+    ) THEN
+        ALTER TABLE leagues
+        ADD CONSTRAINT fk_leagues_pyramid
+        FOREIGN KEY (pyramid_id) REFERENCES pyramids(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_leagues_pyramid_season ON leagues(pyramid_id, season);
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS clubs (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    strength INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS club_id INTEGER;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_teams_club'
+    ) THEN
+        ALTER TABLE teams
+        ADD CONSTRAINT fk_teams_club
+        FOREIGN KEY (club_id) REFERENCES clubs(id) ON DELETE SET NULL;
+    END IF;
+END $$;
+
+-- Every existing team founds a club of its own
+ALTER TABLE clubs ADD COLUMN IF NOT EXISTS origin_team_id INTEGER;
+INSERT INTO clubs (name, strength, origin_team_id)
+SELECT name, strength, id FROM teams WHERE club_id IS NULL;
+UPDATE teams SET club_id = clubs.id FROM clubs WHERE clubs.origin_team_id = teams.id AND teams.club_id IS NULL;
+ALTER TABLE clubs DROP COLUMN IF EXISTS origin_team_id;
+
+-- Seasons of a competition point to its first season
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS competition_id INTEGER;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_leagues_competition'
+    ) THEN
+        ALTER TABLE leagues
+        ADD CONSTRAINT fk_leagues_competition
+        FOREIGN KEY (competition_id) REFERENCES leagues(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Existing standalone leagues are the first season of their competition
+UPDATE leagues SET season = 1 WHERE season = 0 AND format = 'league' AND tournament_id IS NULL AND pyramid_id IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_leagues_competition_season ON leagues(competition_id, season);
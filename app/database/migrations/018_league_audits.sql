@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS league_audits (
+    id SERIAL PRIMARY KEY,
+    league_id INTEGER NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    from_week INTEGER NOT NULL,
+    to_week INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_league_audits_league'
+    ) THEN
+        ALTER TABLE league_audits
+        ADD CONSTRAINT fk_league_audits_league
+        FOREIGN KEY (league_id) REFERENCES leagues(id) ON DELETE CASCADE;
+    END IF;
+END $$;
@@ -0,0 +1,12 @@
+ALTER TABLE league_audits ADD COLUMN IF NOT EXISTS match_id INTEGER;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'fk_league_audits_match'
+    ) THEN
+        ALTER TABLE league_audits
+        ADD CONSTRAINT fk_league_audits_match
+        FOREIGN KEY (match_id) REFERENCES matches(id) ON DELETE SET NULL;
+    END IF;
+END $$;
@@ -0,0 +1 @@
+ALTER TABLE leagues ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,23 @@
+-- Backfills that must run once are recorded here, MigrateAll re-runs every file on each start
+CREATE TABLE IF NOT EXISTS data_backfills (
+    name VARCHAR(255) PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Recompute the away goals of every team from its played league matches once. Knockout and
+-- playoff legs do not count towards the table. Later drift is left for /admin/stats-check to report.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM data_backfills WHERE name = '021_away_goals_backfill') THEN
+        UPDATE team_stats ts
+        SET away_goals_for = COALESCE((
+            SELECT SUM(m.away_score)
+            FROM matches m
+            WHERE m.away_team_id = ts.team_id
+              AND m.played
+              AND m.tie_id IS NULL
+        ), 0);
+
+        INSERT INTO data_backfills (name) VALUES ('021_away_goals_backfill');
+    END IF;
+END $$;
@@ -0,0 +1 @@
+ALTER TABLE league_audits ADD COLUMN IF NOT EXISTS seed BIGINT;
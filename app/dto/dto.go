@@ -5,9 +5,55 @@ import (
 )
 
 type LeagueCreateRequest struct {
-	Name      string        `json:"name" binding:"required"`
-	TeamCount int           `json:"team_count" binding:"required,min=2"`
-	Teams     []TeamRequest `json:"teams" binding:"required,dive"`
+	Name         string               `json:"name" binding:"required"`
+	TeamCount    int                  `json:"team_count" binding:"required,min=2"`
+	Format       string               `json:"format,omitempty"` // "league" (default) or "swiss"
+	Legs         *int                 `json:"legs,omitempty"`   // defaults to 2, a double round robin
+	Rounds       *int                 `json:"rounds,omitempty"` // rounds of a Swiss-system league, defaults to log2 of the team count
+	Teams        []TeamRequest        `json:"teams" binding:"required,dive"`
+	Engine       string               `json:"engine,omitempty"`
+	EngineParams *EngineParamsRequest `json:"engine_params,omitempty"`
+	UseRating    bool                 `json:"use_rating,omitempty"`
+	Seed         *int64               `json:"seed,omitempty"` // generated when not provided
+
+	SimulationIterations int     `json:"simulation_iterations,omitempty"`
+	TargetStdErr         float64 `json:"target_std_err,omitempty"`
+	EstimationStartWeek  *int    `json:"estimation_start_week,omitempty"` // defaults to 0, pre-season
+
+	TieBreakers []string              `json:"tie_breakers,omitempty"`  // defaults to goal difference then goals scored
+	Points      *PointsSystemRequest  `json:"points_system,omitempty"` // defaults to 3/1/0
+	Playoffs    *PlayoffSystemRequest `json:"playoffs,omitempty"`      // defaults to no playoffs
+}
+
+// PlayoffSystemRequest configures the playoffs played after the regular season
+type PlayoffSystemRequest struct {
+	Teams          int  `json:"teams,omitempty"`           // top teams playing off for the title
+	RelegationFrom int  `json:"relegation_from,omitempty"` // highest position of the relegation playoff
+	RelegationTo   int  `json:"relegation_to,omitempty"`   // lowest position of the relegation playoff
+	Legs           *int `json:"legs,omitempty"`            // defaults to 1
+	AwayGoals      bool `json:"away_goals,omitempty"`
+}
+
+// PointsSystemRequest holds optional overrides of the default points system
+type PointsSystemRequest struct {
+	Win                 *int `json:"win,omitempty"`
+	Draw                *int `json:"draw,omitempty"`
+	Loss                *int `json:"loss,omitempty"`
+	GoalsBonusThreshold *int `json:"goals_bonus_threshold,omitempty"`
+	GoalsBonus          *int `json:"goals_bonus,omitempty"`
+	LosingBonusMargin   *int `json:"losing_bonus_margin,omitempty"`
+	LosingBonus         *int `json:"losing_bonus,omitempty"`
+	NoDraws             bool `json:"no_draws,omitempty"`
+	ShootoutWin         *int `json:"shootout_win,omitempty"`
+	ShootoutLoss        *int `json:"shootout_loss,omitempty"`
+}
+
+// EngineParamsRequest holds optional overrides of the default engine parameters
+type EngineParamsRequest struct {
+	BaseGoals      *float64 `json:"base_goals,omitempty"`
+	HomeAdvantage  *float64 `json:"home_advantage,omitempty"`
+	StrengthWeight *float64 `json:"strength_weight,omitempty"`
+	FormWeight     *float64 `json:"form_weight,omitempty"`
 }
 
 type TeamRequest struct {
@@ -16,21 +62,45 @@ type TeamRequest struct {
 }
 
 type LeagueResponse struct {
-	ID        uint           `json:"id"`
-	Name      string         `json:"name"`
-	TeamCount int            `json:"team_count"`
-	MaxWeeks  int            `json:"max_weeks"`
-	CurrWeek  int            `json:"curr_week"`
-	Teams     []models.Team  `json:"teams,omitempty"`
-	Matches   []models.Match `json:"matches,omitempty"`
+	ID           uint                `json:"id"`
+	Name         string              `json:"name"`
+	Format       string              `json:"format"`
+	TeamCount    int                 `json:"team_count"`
+	MaxWeeks     int                 `json:"max_weeks"`
+	Legs         int                 `json:"legs"`
+	CurrWeek     int                 `json:"curr_week"`
+	Engine       string              `json:"engine"`
+	EngineParams models.EngineParams `json:"engine_params"`
+	UseRating    bool                `json:"use_rating"`
+	Seed         int64               `json:"seed"`
+
+	SimulationIterations int     `json:"simulation_iterations"`
+	TargetStdErr         float64 `json:"target_std_err"`
+	EstimationStartWeek  int     `json:"estimation_start_week"`
+
+	TieBreakers []string             `json:"tie_breakers"`
+	Points      models.PointsSystem  `json:"points_system"`
+	Playoffs    models.PlayoffSystem `json:"playoffs"`
+
+	CompetitionID *uint `json:"competition_id,omitempty"` // first season of the competition
+	Season        int   `json:"season"`
+
+	Teams   []models.Team  `json:"teams,omitempty"`
+	Matches []models.Match `json:"matches,omitempty"`
+	Byes    []models.Bye   `json:"byes,omitempty"` // teams sitting out a week, only with an odd number of teams
 }
 
 type Week struct {
 	LeagueID  uint               `json:"league_id"`
 	Week      int                `json:"week"`
 	Matches   []models.Match     `json:"matches,omitempty"`
+	Byes      []models.Bye       `json:"byes,omitempty"`
 	TeamStats []models.TeamStats `json:"team_stats,omitempty"`
+	Ties      []models.Tie       `json:"ties,omitempty"` // playoff ties drawn or played this week
 	Champion  models.Team        `json:"champion,omitempty"`
+
+	RelegationSurvivor *models.Team  `json:"relegation_survivor,omitempty"` // winner of the relegation playoff, set the week its final is played
+	Relegated          []models.Team `json:"relegated,omitempty"`           // losers of the relegation playoff
 }
 
 type ChampionshipEstimation struct {
@@ -38,14 +108,237 @@ type ChampionshipEstimation struct {
 	Week       int     `json:"week"`
 	TeamID     uint    `json:"team_id"`
 	Estimation float32 `json:"estimation"`
+	Iterations int     `json:"iterations"` // Monte Carlo iterations actually run
+	StdErr     float32 `json:"std_err"`    // standard error of the estimation
+
+	// Status and Guarantee are mathematical certainties, unlike the probabilistic estimation
+	Status    string         `json:"status,omitempty"`
+	Guarantee *TeamGuarantee `json:"guarantee,omitempty"`
+}
+
+const (
+	StatusClinched   = "clinched"
+	StatusEliminated = "eliminated"
+	StatusContending = "contending"
+)
+
+// TeamGuarantee holds what is mathematically certain about a team's final standing
+type TeamGuarantee struct {
+	TeamID                  uint `json:"team_id"`
+	ClinchedTitle           bool `json:"clinched_title"`
+	Eliminated              bool `json:"eliminated"`                // can no longer win the title
+	HighestPossiblePosition int  `json:"highest_possible_position"` // cannot finish above this position
+	LowestPossiblePosition  int  `json:"lowest_possible_position"`  // guaranteed to finish in this position or higher
+	MagicNumber             *int `json:"magic_number"`              // points needed to clinch whatever the rivals do, nil if out of reach
+}
+
+// TeamProjection is the projected end of season outcome of a team
+type TeamProjection struct {
+	TeamID                uint            `json:"team_id"`
+	PositionProbabilities []float32       `json:"position_probabilities"` // index 0 is the probability of finishing first
+	ExpectedPoints        float32         `json:"expected_points"`
+	PointsDistribution    map[int]float32 `json:"points_distribution"` // final points -> probability
+
+	PromotionProbability  *float32 `json:"promotion_probability,omitempty"`  // only for divisions promoting teams
+	RelegationProbability *float32 `json:"relegation_probability,omitempty"` // only for divisions relegating teams
+}
+
+// LeagueProjection is the outcome of a Monte Carlo estimation of the remaining season
+type LeagueProjection struct {
+	LeagueID    uint                     `json:"league_id"`
+	Week        int                      `json:"week"`
+	Iterations  int                      `json:"iterations"`
+	Estimations []ChampionshipEstimation `json:"estimations,omitempty"`
+	Teams       []TeamProjection         `json:"teams"`
+}
+
+// EstimationRequest overrides the league's Monte Carlo settings for a single request
+type EstimationRequest struct {
+	Iterations int
+	StdErr     float64
+}
+
+// RewindRequest names the week to rewind a league to. The matches after it keep their seeds
+// unless a new seed is given or Reseed asks for a random one.
+type RewindRequest struct {
+	Week   int
+	Seed   *int64
+	Reseed bool
 }
 type LeagueState struct {
 	LeagueID         uint               `json:"league_id"`
 	Week             int                `json:"week"`
 	Teams            []models.Team      `json:"teams"`
 	RemainingMatches []models.Match     `json:"matches"`
+	PlayedMatches    []models.Match     `json:"played_matches"` // used by the head-to-head tie-breakers
 	TeamStats        []models.TeamStats `json:"team_stats"`
+	PlayoffTies      []models.Tie       `json:"playoff_ties,omitempty"`    // title playoff ties drawn so far
+	RelegationTies   []models.Tie       `json:"relegation_ties,omitempty"` // relegation playoff ties drawn so far
+	PlayoffRound     int                `json:"playoff_round,omitempty"`   // playoff round to be played
+}
+
+// Standings is the league table ordered with the league's tie-breakers
+type Standings struct {
+	LeagueID    uint            `json:"league_id"`
+	Week        int             `json:"week"`
+	TieBreakers []string        `json:"tie_breakers"`
+	LotsSeed    int64           `json:"lots_seed"` // seed of the drawing of lots, recorded so the draw can be reproduced
+	Table       []StandingEntry `json:"table"`
+}
+
+type StandingEntry struct {
+	Position int    `json:"position"`
+	TeamName string `json:"team_name"`
+	models.TeamStats
+}
+
+// CupCreateRequest creates a single-elimination cup between the given teams
+type CupCreateRequest struct {
+	Name         string               `json:"name" binding:"required"`
+	Teams        []TeamRequest        `json:"teams" binding:"required,dive"`
+	Legs         *int                 `json:"legs,omitempty"`       // legs of every tie, 1 or 2, defaults to 1
+	AwayGoals    bool                 `json:"away_goals,omitempty"` // away goals break level two-legged ties
+	Seeded       bool                 `json:"seeded,omitempty"`     // seeds the bracket by strength instead of drawing it
+	Engine       string               `json:"engine,omitempty"`
+	EngineParams *EngineParamsRequest `json:"engine_params,omitempty"`
+	UseRating    bool                 `json:"use_rating,omitempty"`
+	Seed         *int64               `json:"seed,omitempty"` // generated when not provided
+
+	SimulationIterations int     `json:"simulation_iterations,omitempty"`
+	TargetStdErr         float64 `json:"target_std_err,omitempty"`
+}
+
+// Bracket is the state of a knockout competition, its ties ordered by round and slot
+type Bracket struct {
+	LeagueID     uint          `json:"league_id"`
+	Name         string        `json:"name"`
+	Rounds       int           `json:"rounds"`
+	CurrentRound int           `json:"current_round"` // rounds + 1 once the cup is decided
+	Legs         int           `json:"legs"`
+	AwayGoals    bool          `json:"away_goals"`
+	Seeded       bool          `json:"seeded"`
+	Seed         int64         `json:"seed"`
+	Teams        []models.Team `json:"teams,omitempty"`
+	Ties         []models.Tie  `json:"ties"`
+	Champion     *models.Team  `json:"champion,omitempty"`
 }
+
+// CupRound is the outcome of a simulated knockout round
+type CupRound struct {
+	LeagueID uint         `json:"league_id"`
+	Round    int          `json:"round"`
+	Ties     []models.Tie `json:"ties"`
+	Champion *models.Team `json:"champion,omitempty"`
+}
+
+// KnockoutState is the input of a Monte Carlo estimation of a knockout competition
+type KnockoutState struct {
+	LeagueID  uint               `json:"league_id"`
+	Round     int                `json:"round"`  // round to be played next
+	Rounds    int                `json:"rounds"` // number of rounds of the bracket
+	Teams     []models.Team      `json:"teams"`
+	TeamStats []models.TeamStats `json:"team_stats"`
+	Ties      []models.Tie       `json:"ties"` // every tie drawn so far
+}
+
+// TeamKnockoutProjection is the projected outcome of a team in a knockout competition
+type TeamKnockoutProjection struct {
+	TeamID             uint      `json:"team_id"`
+	RoundProbabilities []float32 `json:"round_probabilities"` // index r-1 is the probability of playing round r, the last one of winning the cup
+	WinStdErr          float32   `json:"win_std_err"`         // standard error of the probability of winning the cup
+}
+
+// KnockoutProjection is the outcome of a Monte Carlo estimation of the remaining knockout rounds
+type KnockoutProjection struct {
+	LeagueID   uint                     `json:"league_id"`
+	Round      int                      `json:"round"`
+	Iterations int                      `json:"iterations"`
+	Teams      []TeamKnockoutProjection `json:"teams"`
+}
+
+// TournamentCreateRequest creates a group stage followed by a knockout stage between the given teams
+type TournamentCreateRequest struct {
+	Name         string               `json:"name" binding:"required"`
+	Teams        []TeamRequest        `json:"teams" binding:"required,dive"`
+	GroupCount   int                  `json:"group_count" binding:"required,min=1"`
+	Qualifiers   *int                 `json:"qualifiers,omitempty"` // teams advancing from every group, defaults to 2
+	Seeded       bool                 `json:"seeded,omitempty"`     // draws the groups from pots of strength
+	Legs         *int                 `json:"legs,omitempty"`       // legs of the group round robins, defaults to 1
+	KnockoutLegs *int                 `json:"knockout_legs,omitempty"`
+	AwayGoals    bool                 `json:"away_goals,omitempty"`
+	Engine       string               `json:"engine,omitempty"`
+	EngineParams *EngineParamsRequest `json:"engine_params,omitempty"`
+	UseRating    bool                 `json:"use_rating,omitempty"`
+	Seed         *int64               `json:"seed,omitempty"` // generated when not provided
+
+	SimulationIterations int     `json:"simulation_iterations,omitempty"`
+	TargetStdErr         float64 `json:"target_std_err,omitempty"`
+
+	TieBreakers []string             `json:"tie_breakers,omitempty"`
+	Points      *PointsSystemRequest `json:"points_system,omitempty"`
+}
+
+// TournamentResponse is the state of a tournament, its group standings and its knockout bracket
+type TournamentResponse struct {
+	ID         uint              `json:"id"`
+	Name       string            `json:"name"`
+	Stage      string            `json:"stage"` // "groups", "knockout" or "finished"
+	Qualifiers int               `json:"qualifiers"`
+	Seed       int64             `json:"seed"`
+	Groups     []TournamentGroup `json:"groups"`
+	Knockout   *Bracket          `json:"knockout,omitempty"`
+	Champion   *models.Team      `json:"champion,omitempty"`
+}
+
+// TournamentGroup is a group of a tournament with its current standings
+type TournamentGroup struct {
+	LeagueID  uint            `json:"league_id"`
+	Name      string          `json:"name"`
+	CurrWeek  int             `json:"curr_week"`
+	MaxWeeks  int             `json:"max_weeks"`
+	Standings []StandingEntry `json:"standings"`
+}
+
+// TournamentState is the input of a Monte Carlo estimation of a tournament
+type TournamentState struct {
+	TournamentID uint                   `json:"tournament_id"`
+	Qualifiers   int                    `json:"qualifiers"`
+	Groups       []TournamentGroupState `json:"groups"`
+	Knockout     *KnockoutState         `json:"knockout,omitempty"` // set once the knockout stage is drawn
+}
+
+// TournamentGroupState is the state of a group of a tournament
+type TournamentGroupState struct {
+	League models.League `json:"league"`
+	LeagueState
+}
+
+// TeamTournamentProjection is the projected outcome of a team in a tournament
+type TeamTournamentProjection struct {
+	TeamID             uint      `json:"team_id"`
+	GroupID            uint      `json:"group_id"`
+	RoundProbabilities []float32 `json:"round_probabilities"` // index r-1 is the probability of playing knockout round r, index 0 of qualifying, the last one of winning
+	WinStdErr          float32   `json:"win_std_err"`
+}
+
+// TournamentProjection is the outcome of a Monte Carlo estimation of the rest of a tournament
+type TournamentProjection struct {
+	TournamentID uint                       `json:"tournament_id"`
+	Rounds       int                        `json:"rounds"` // knockout rounds
+	Iterations   int                        `json:"iterations"`
+	Teams        []TeamTournamentProjection `json:"teams"`
+}
+
+// MatchResultRequest is the corrected result of a played match
+type MatchResultRequest struct {
+	HomeScore int `json:"home_score"`
+	AwayScore int `json:"away_score"`
+
+	// Penalties decide level matches of leagues without draws
+	HomePenalties *int `json:"home_penalties,omitempty"`
+	AwayPenalties *int `json:"away_penalties,omitempty"`
+}
+
 type UserPlayedMatch struct {
 	LeagueID   uint `json:"league_id"`
 	Week       int  `json:"week"`
@@ -54,9 +347,108 @@ type UserPlayedMatch struct {
 	AwayTeamID uint `json:"away_team_id"`
 	HomeScore  int  `json:"home_score"`
 	AwayScore  int  `json:"away_score"`
+
+	// Penalties decide level matches of leagues without draws
+	HomePenalties *int `json:"home_penalties,omitempty"`
+	AwayPenalties *int `json:"away_penalties,omitempty"`
 }
 type Champion struct {
 	TeamID   uint   `json:"team_id" gorm:"primaryKey"`
 	LeagueID uint   `json:"league_id" gorm:"primaryKey"`
 	TeamName string `json:"team_name"`
 }
+
+// PyramidCreateRequest creates the first season of a pyramid of divisions, the top division first
+type PyramidCreateRequest struct {
+	Name         string               `json:"name" binding:"required"`
+	Divisions    []DivisionRequest    `json:"divisions" binding:"required,dive"`
+	Swaps        *int                 `json:"swaps,omitempty"` // teams promoted and relegated between neighbouring divisions, defaults to 1
+	Legs         *int                 `json:"legs,omitempty"`  // defaults to 2, a double round robin
+	Engine       string               `json:"engine,omitempty"`
+	EngineParams *EngineParamsRequest `json:"engine_params,omitempty"`
+	UseRating    bool                 `json:"use_rating,omitempty"`
+	Seed         *int64               `json:"seed,omitempty"` // generated when not provided
+
+	SimulationIterations int     `json:"simulation_iterations,omitempty"`
+	TargetStdErr         float64 `json:"target_std_err,omitempty"`
+
+	TieBreakers []string             `json:"tie_breakers,omitempty"`
+	Points      *PointsSystemRequest `json:"points_system,omitempty"`
+}
+
+type DivisionRequest struct {
+	Name  string        `json:"name,omitempty"` // defaults to "<pyramid> Division <tier>"
+	Teams []TeamRequest `json:"teams" binding:"required,dive"`
+}
+
+// PyramidResponse is the current season of a pyramid and the standings of its divisions
+type PyramidResponse struct {
+	ID        uint              `json:"id"`
+	Name      string            `json:"name"`
+	Season    int               `json:"season"`
+	Swaps     int               `json:"swaps"`
+	Seed      int64             `json:"seed"`
+	Finished  bool              `json:"finished"` // every division is played and the next season can start
+	Divisions []PyramidDivision `json:"divisions"`
+}
+
+type PyramidDivision struct {
+	LeagueID  uint            `json:"league_id"`
+	Name      string          `json:"name"`
+	Division  int             `json:"division"`
+	CurrWeek  int             `json:"curr_week"`
+	MaxWeeks  int             `json:"max_weeks"`
+	Promoted  int             `json:"promoted"`
+	Relegated int             `json:"relegated"`
+	Standings []StandingEntry `json:"standings"`
+}
+
+// PyramidProjection is the outcome of a Monte Carlo estimation of the rest of a pyramid's season
+type PyramidProjection struct {
+	PyramidID uint               `json:"pyramid_id"`
+	Season    int                `json:"season"`
+	Divisions []LeagueProjection `json:"divisions"`
+}
+
+// NextSeasonRequest configures the season following a finished league
+type NextSeasonRequest struct {
+	StrengthDrift int `json:"strength_drift,omitempty"` // strength the champion gains and the last team loses, 0 keeps every strength
+}
+
+// AllTimeTable sums the seasons of a competition club by club
+type AllTimeTable struct {
+	CompetitionID uint           `json:"competition_id"`
+	Seasons       int            `json:"seasons"`
+	Table         []AllTimeEntry `json:"table"`
+}
+
+type AllTimeEntry struct {
+	Position     int    `json:"position"`
+	ClubID       uint   `json:"club_id"`
+	ClubName     string `json:"club_name"`
+	Seasons      int    `json:"seasons"`
+	Titles       int    `json:"titles"`
+	Played       int    `json:"played"`
+	Won          int    `json:"won"`
+	Draw         int    `json:"draw"`
+	Lost         int    `json:"lost"`
+	GoalsFor     int    `json:"goals_for"`
+	GoalsAgainst int    `json:"goals_against"`
+	GoalDiff     int    `json:"goal_diff"`
+	Points       int    `json:"points"`
+}
+
+// StatsReport compares the stored team stats of a league with the stats its matches add up to
+type StatsReport struct {
+	LeagueID      uint               `json:"league_id"`
+	LeagueName    string             `json:"league_name"`
+	Consistent    bool               `json:"consistent"`
+	Discrepancies []StatsDiscrepancy `json:"discrepancies,omitempty"`
+}
+
+// StatsDiscrepancy is a team whose stored stats disagree with its matches
+type StatsDiscrepancy struct {
+	TeamID    uint             `json:"team_id"`
+	Stored    models.TeamStats `json:"stored"`
+	Projected models.TeamStats `json:"projected"`
+}
@@ -3,6 +3,8 @@ package helpers
 import (
 	"fmt"
 	"insider-case/app/dto"
+	"insider-case/app/models"
+	"insider-case/app/utils"
 )
 
 type ValidationError struct {
@@ -21,12 +23,6 @@ func ValidateTeamCount(count int) error {
 			Message: "must have at least 2 teams",
 		}
 	}
-	if count%2 != 0 {
-		return &ValidationError{
-			Field:   "team_count",
-			Message: "must be an even number",
-		}
-	}
 	return nil
 }
 func ValidateTeamStrength(teams []dto.TeamRequest) error {
@@ -41,6 +37,308 @@ func ValidateTeamStrength(teams []dto.TeamRequest) error {
 	return nil
 }
 
-func CalculateMaxWeeks(TeamCount int) int {
-	return ((2 * TeamCount) - 2)
+func ValidateEngine(engine string) error {
+	if engine != "" && !utils.IsSupportedEngine(engine) {
+		return &ValidationError{
+			Field:   "engine",
+			Message: fmt.Sprintf("unknown match engine %s", engine),
+		}
+	}
+	return nil
+}
+
+func ValidateEngineParams(params *dto.EngineParamsRequest) error {
+	if params == nil {
+		return nil
+	}
+	if params.BaseGoals != nil && *params.BaseGoals <= 0 {
+		return &ValidationError{Field: "base_goals", Message: "must be greater than 0"}
+	}
+	if params.HomeAdvantage != nil && *params.HomeAdvantage <= 0 {
+		return &ValidationError{Field: "home_advantage", Message: "must be greater than 0"}
+	}
+	if params.StrengthWeight != nil && *params.StrengthWeight < 0 {
+		return &ValidationError{Field: "strength_weight", Message: "cannot be negative"}
+	}
+	if params.FormWeight != nil && *params.FormWeight < 0 {
+		return &ValidationError{Field: "form_weight", Message: "cannot be negative"}
+	}
+	return nil
+}
+
+func ValidateSimulationSettings(iterations int, targetStdErr float64) error {
+	if iterations != 0 && (iterations < utils.MinSimulationIterations || iterations > utils.MaxSimulationIterations) {
+		return &ValidationError{
+			Field:   "simulation_iterations",
+			Message: fmt.Sprintf("must be between %d and %d", utils.MinSimulationIterations, utils.MaxSimulationIterations),
+		}
+	}
+	if targetStdErr < 0 || targetStdErr >= 0.5 {
+		return &ValidationError{
+			Field:   "target_std_err",
+			Message: "must be between 0 and 0.5",
+		}
+	}
+	return nil
+}
+
+func ValidateEstimationStartWeek(startWeek *int, maxWeeks int) error {
+	if startWeek != nil && (*startWeek < 0 || *startWeek > maxWeeks) {
+		return &ValidationError{
+			Field:   "estimation_start_week",
+			Message: fmt.Sprintf("must be between 0 and %d", maxWeeks),
+		}
+	}
+	return nil
+}
+
+func ValidateTieBreakers(tieBreakers []string) error {
+	seen := make(map[string]bool, len(tieBreakers))
+	for _, tieBreaker := range tieBreakers {
+		if !utils.IsSupportedTieBreaker(tieBreaker) {
+			return &ValidationError{
+				Field:   "tie_breakers",
+				Message: fmt.Sprintf("unknown tie-breaker %s", tieBreaker),
+			}
+		}
+		if seen[tieBreaker] {
+			return &ValidationError{
+				Field:   "tie_breakers",
+				Message: fmt.Sprintf("tie-breaker %s is listed more than once", tieBreaker),
+			}
+		}
+		seen[tieBreaker] = true
+	}
+	return nil
+}
+
+func ValidatePointsSystem(points models.PointsSystem) error {
+	for _, value := range []struct {
+		field string
+		value int
+	}{
+		{"win", points.Win},
+		{"draw", points.Draw},
+		{"loss", points.Loss},
+		{"goals_bonus_threshold", points.GoalsBonusThreshold},
+		{"goals_bonus", points.GoalsBonus},
+		{"losing_bonus_margin", points.LosingBonusMargin},
+		{"losing_bonus", points.LosingBonus},
+		{"shootout_win", points.ShootoutWin},
+		{"shootout_loss", points.ShootoutLoss},
+	} {
+		if value.value < 0 {
+			return &ValidationError{Field: value.field, Message: "cannot be negative"}
+		}
+	}
+	if points.Win <= points.Loss {
+		return &ValidationError{Field: "win", Message: "must be greater than the points of a loss"}
+	}
+	if !points.NoDraws && (points.Draw > points.Win || points.Draw < points.Loss) {
+		return &ValidationError{Field: "draw", Message: "must be between the points of a loss and a win"}
+	}
+	if points.NoDraws && (points.ShootoutWin > points.Win || points.ShootoutLoss < points.Loss || points.ShootoutLoss > points.ShootoutWin) {
+		return &ValidationError{Field: "shootout_win", Message: "shootout points must be between the points of a loss and a win, the winner getting at least as many as the loser"}
+	}
+	return nil
+}
+
+// ValidatePenalties checks that penalties are given exactly for the level matches of leagues without draws
+func ValidatePenalties(match dto.UserPlayedMatch, noDraws bool) error {
+	hasPenalties := match.HomePenalties != nil || match.AwayPenalties != nil
+	level := match.HomeScore == match.AwayScore
+	switch {
+	case noDraws && level && (match.HomePenalties == nil || match.AwayPenalties == nil):
+		return &ValidationError{Field: "penalties", Message: fmt.Sprintf("match %d is level and must be decided on penalties", match.MatchID)}
+	case hasPenalties && (!noDraws || !level):
+		return &ValidationError{Field: "penalties", Message: fmt.Sprintf("match %d cannot be decided on penalties", match.MatchID)}
+	case hasPenalties && (*match.HomePenalties < 0 || *match.AwayPenalties < 0 || *match.HomePenalties == *match.AwayPenalties):
+		return &ValidationError{Field: "penalties", Message: fmt.Sprintf("penalties of match %d must have a winner", match.MatchID)}
+	}
+	return nil
+}
+
+// ValidateWeekResults requires the results to cover exactly the unplayed fixtures of the current week
+// of the league, so no result is applied twice and no fixture is left unplayed
+func ValidateWeekResults(league models.League, fixtures []models.Match, matches []dto.UserPlayedMatch) error {
+	unplayed := make(map[uint]models.Match)
+	for _, fixture := range fixtures {
+		if !fixture.Played {
+			unplayed[fixture.ID] = fixture
+		}
+	}
+	entered := make(map[uint]bool)
+	for _, match := range matches {
+		if match.LeagueID != league.ID || match.Week != league.CurrWeek {
+			return &ValidationError{Field: "match_id", Message: fmt.Sprintf("match %d is not in week %d of league %d", match.MatchID, league.CurrWeek, league.ID)}
+		}
+		fixture, ok := unplayed[match.MatchID]
+		if !ok || entered[match.MatchID] {
+			return &ValidationError{Field: "match_id", Message: fmt.Sprintf("match %d is not an unplayed match of week %d", match.MatchID, league.CurrWeek)}
+		}
+		if match.HomeTeamID != fixture.HomeTeamID || match.AwayTeamID != fixture.AwayTeamID {
+			return &ValidationError{Field: "match_id", Message: fmt.Sprintf("teams of match %d do not match its fixture", match.MatchID)}
+		}
+		entered[match.MatchID] = true
+	}
+	if len(entered) != len(unplayed) {
+		return &ValidationError{Field: "matches", Message: fmt.Sprintf("results of all %d unplayed matches of week %d are required", len(unplayed), league.CurrWeek)}
+	}
+	return nil
+}
+
+func ValidateLegs(legs *int) error {
+	if legs != nil && (*legs < utils.MinLegs || *legs > utils.MaxLegs) {
+		return &ValidationError{
+			Field:   "legs",
+			Message: fmt.Sprintf("must be between %d and %d", utils.MinLegs, utils.MaxLegs),
+		}
+	}
+	return nil
+}
+
+func ValidateKnockoutLegs(legs *int) error {
+	if legs != nil && (*legs < utils.MinKnockoutLegs || *legs > utils.MaxKnockoutLegs) {
+		return &ValidationError{
+			Field:   "legs",
+			Message: fmt.Sprintf("must be between %d and %d", utils.MinKnockoutLegs, utils.MaxKnockoutLegs),
+		}
+	}
+	return nil
+}
+
+// ValidateLeagueFormat checks the format of a league and, for a Swiss-system league, that its
+// rounds can be played without a rematch
+func ValidateLeagueFormat(format string, legs, rounds *int, teamCount int) error {
+	switch format {
+	case "", utils.FormatLeague:
+		if rounds != nil {
+			return &ValidationError{
+				Field:   "rounds",
+				Message: "only Swiss-system leagues set their rounds, round robins set their legs",
+			}
+		}
+	case utils.FormatSwiss:
+		if legs != nil {
+			return &ValidationError{
+				Field:   "legs",
+				Message: "Swiss-system leagues set their rounds, not their legs",
+			}
+		}
+		if rounds != nil && (*rounds < 1 || *rounds > utils.MaxSwissRounds(teamCount)) {
+			return &ValidationError{
+				Field:   "rounds",
+				Message: fmt.Sprintf("must be between 1 and %d", utils.MaxSwissRounds(teamCount)),
+			}
+		}
+	default:
+		return &ValidationError{
+			Field:   "format",
+			Message: fmt.Sprintf("must be %q or %q", utils.FormatLeague, utils.FormatSwiss),
+		}
+	}
+	return nil
+}
+
+// ValidatePlayoffs checks the title playoff is played by at least two teams of the league and the
+// relegation playoff by a range of at least two positions below the title playoff
+func ValidatePlayoffs(playoffs *dto.PlayoffSystemRequest, teamCount int) error {
+	if playoffs == nil {
+		return nil
+	}
+	if playoffs.Teams != 0 && (playoffs.Teams < 2 || playoffs.Teams > teamCount) {
+		return &ValidationError{
+			Field:   "playoffs.teams",
+			Message: fmt.Sprintf("must be 0 or between 2 and %d", teamCount),
+		}
+	}
+	if playoffs.RelegationFrom != 0 || playoffs.RelegationTo != 0 {
+		if playoffs.RelegationFrom < 1 || playoffs.RelegationFrom >= playoffs.RelegationTo || playoffs.RelegationTo > teamCount {
+			return &ValidationError{
+				Field:   "playoffs.relegation_from",
+				Message: fmt.Sprintf("relegation playoff positions must satisfy 1 <= from < to <= %d", teamCount),
+			}
+		}
+		if playoffs.RelegationFrom <= playoffs.Teams {
+			return &ValidationError{
+				Field:   "playoffs.relegation_from",
+				Message: "relegation playoff cannot overlap the title playoff",
+			}
+		}
+	}
+	if playoffs.Legs != nil && (*playoffs.Legs < utils.MinKnockoutLegs || *playoffs.Legs > utils.MaxKnockoutLegs) {
+		return &ValidationError{
+			Field:   "playoffs.legs",
+			Message: fmt.Sprintf("must be between %d and %d", utils.MinKnockoutLegs, utils.MaxKnockoutLegs),
+		}
+	}
+	return nil
+}
+
+// ValidatePyramid checks a pyramid has at least two divisions and that no team of a division
+// can be both promoted and relegated
+func ValidatePyramid(divisions []dto.DivisionRequest, swaps int) error {
+	if len(divisions) < 2 {
+		return &ValidationError{
+			Field:   "divisions",
+			Message: "a pyramid needs at least 2 divisions",
+		}
+	}
+	if swaps < 1 {
+		return &ValidationError{
+			Field:   "swaps",
+			Message: "must be at least 1",
+		}
+	}
+	for d, division := range divisions {
+		if err := ValidateTeamCount(len(division.Teams)); err != nil {
+			return err
+		}
+		if err := ValidateTeamStrength(division.Teams); err != nil {
+			return err
+		}
+		promoted, relegated := utils.DivisionMoves(d+1, len(divisions), swaps)
+		if promoted+relegated >= len(division.Teams) {
+			return &ValidationError{
+				Field:   "swaps",
+				Message: fmt.Sprintf("division %d has %d teams, too few to promote %d and relegate %d", d+1, len(division.Teams), promoted, relegated),
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateStrengthDrift checks the strength drift between seasons stays within the strength range
+func ValidateStrengthDrift(drift int) error {
+	if drift < 0 || drift > utils.MaxTeamStrength-utils.MinTeamStrength {
+		return &ValidationError{
+			Field:   "strength_drift",
+			Message: fmt.Sprintf("must be between 0 and %d", utils.MaxTeamStrength-utils.MinTeamStrength),
+		}
+	}
+	return nil
+}
+
+// ValidateTournamentGroups checks the teams split into equal groups of at least two teams and
+// that at least two teams qualify for the knockout stage
+func ValidateTournamentGroups(teamCount, groupCount, qualifiers int) error {
+	if groupCount < 1 || teamCount%groupCount != 0 || teamCount/groupCount < 2 {
+		return &ValidationError{
+			Field:   "group_count",
+			Message: fmt.Sprintf("%d teams cannot be split into %d groups of at least 2 teams", teamCount, groupCount),
+		}
+	}
+	if qualifiers < 1 || qualifiers > teamCount/groupCount || qualifiers*groupCount < 2 {
+		return &ValidationError{
+			Field:   "qualifiers",
+			Message: fmt.Sprintf("must be between 1 and %d with at least 2 teams qualifying", teamCount/groupCount),
+		}
+	}
+	return nil
+}
+
+// CalculateMaxWeeks returns the weeks of a round robin with the given legs, an odd number of
+// teams takes one more week per leg as every team has a bye
+func CalculateMaxWeeks(TeamCount int, legs int) int {
+	return legs * utils.RoundsPerLeg(TeamCount)
 }
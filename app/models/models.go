@@ -1,23 +1,101 @@
 package models
 
+import "time"
+
 type League struct {
-	ID        uint    `json:"id" gorm:"primaryKey"`
-	Name      string  `json:"name"`
-	TeamCount int     `json:"team_count"`
-	MaxWeeks  int     `json:"max_weeks"`
-	CurrWeek  int     `json:"curr_week"`
-	Teams     []Team  `json:"teams,omitempty" gorm:"foreignKey:LeagueID"`
-	Matches   []Match `json:"matches,omitempty" gorm:"foreignKey:LeagueID"`
+	ID           uint         `json:"id" gorm:"primaryKey"`
+	Name         string       `json:"name"`
+	Format       string       `json:"format"` // "league" or "knockout"
+	TeamCount    int          `json:"team_count"`
+	MaxWeeks     int          `json:"max_weeks"`
+	Legs         int          `json:"legs"` // times every team meets every other team, 2 for a double round robin
+	CurrWeek     int          `json:"curr_week"`
+	Engine       string       `json:"engine"`
+	EngineParams EngineParams `json:"engine_params" gorm:"embedded"`
+	UseRating    bool         `json:"use_rating"` // simulate with the live Elo rating instead of the static strength
+	Seed         int64        `json:"seed"`       // base seed every simulation of the league is derived from
+	Version      int          `json:"version"`    // bumped by every change of the week or history, guards against concurrent changes
+
+	SimulationIterations int     `json:"simulation_iterations"` // Monte Carlo iterations per estimation
+	TargetStdErr         float64 `json:"target_std_err"`        // stop estimating early below this standard error, 0 disables
+	EstimationStartWeek  int     `json:"estimation_start_week"` // weeks to play before estimations are published, 0 for pre-season
+
+	TournamentID  *uint `json:"tournament_id,omitempty"`  // tournament the league is a group of
+	PyramidID     *uint `json:"pyramid_id,omitempty"`     // pyramid the league is a division of
+	CompetitionID *uint `json:"competition_id,omitempty"` // first season of the competition, nil for the first season itself
+	Season        int   `json:"season,omitempty"`         // season of the competition or pyramid the league is played in
+	Division      int   `json:"division,omitempty"`       // tier of the division in the pyramid, 1 for the top
+	Promoted      int   `json:"promoted"`                 // top teams moving up a division at the end of the season
+	Relegated     int   `json:"relegated"`                // bottom teams moving down a division at the end of the season
+
+	TieBreakers []string      `json:"tie_breakers" gorm:"serializer:json;type:jsonb"` // ordered criteria separating teams level on points
+	Points      PointsSystem  `json:"points_system" gorm:"embedded;embeddedPrefix:points_"`
+	Playoffs    PlayoffSystem `json:"playoffs" gorm:"embedded;embeddedPrefix:playoff_"`
+	AwayGoals   bool          `json:"away_goals"` // away goals break level aggregates of two-legged knockout ties
+	Seeded      bool          `json:"seeded"`     // knockout brackets are seeded by strength instead of drawn
+	Teams       []Team        `json:"teams,omitempty" gorm:"foreignKey:LeagueID"`
+	Matches     []Match       `json:"matches,omitempty" gorm:"foreignKey:LeagueID"`
+	Byes        []Bye         `json:"byes,omitempty" gorm:"foreignKey:LeagueID"`
+}
+
+// EngineParams tunes the goal model of the poisson match engine
+type EngineParams struct {
+	BaseGoals      float64 `json:"base_goals"`      // expected goals of a side between equal teams
+	HomeAdvantage  float64 `json:"home_advantage"`  // multiplier on the home side's expected goals
+	StrengthWeight float64 `json:"strength_weight"` // exponent applied to the strength ratio
+	FormWeight     float64 `json:"form_weight"`     // exponent applied to the form factor
+}
+
+// PointsSystem holds the scoring rules of a league
+type PointsSystem struct {
+	Win                 int  `json:"win"`
+	Draw                int  `json:"draw"`
+	Loss                int  `json:"loss"`
+	GoalsBonusThreshold int  `json:"goals_bonus_threshold"` // goals a side must score to get the goals bonus, 0 disables it
+	GoalsBonus          int  `json:"goals_bonus"`
+	LosingBonusMargin   int  `json:"losing_bonus_margin"` // largest defeat margin getting the losing bonus, 0 disables it
+	LosingBonus         int  `json:"losing_bonus"`
+	NoDraws             bool `json:"no_draws"` // level matches are decided by a penalty shootout
+	ShootoutWin         int  `json:"shootout_win"`
+	ShootoutLoss        int  `json:"shootout_loss"`
+}
+
+// PlayoffSystem configures the playoffs played after the regular season, between teams seeded by
+// their final position
+type PlayoffSystem struct {
+	Teams          int  `json:"teams"`           // top teams playing off for the title, 0 for none
+	RelegationFrom int  `json:"relegation_from"` // highest position of the relegation playoff, 0 for none
+	RelegationTo   int  `json:"relegation_to"`   // lowest position of the relegation playoff
+	Legs           int  `json:"legs"`            // legs of every playoff tie
+	AwayGoals      bool `json:"away_goals"`
+}
+
+// Club is a club playing in competitions season after season, through a team of every season
+type Club struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Name     string `json:"name"`
+	Strength int    `json:"strength"` // strength of the club's latest team
 }
 
 type Team struct {
 	ID       uint      `json:"id" gorm:"primaryKey"`
 	LeagueID uint      `json:"league_id"`
+	ClubID   *uint     `json:"club_id,omitempty"`
 	Name     string    `json:"name"`
 	Strength int       `json:"strength"`
+	Rating   float64   `json:"rating"`
 	Stats    TeamStats `json:"stats,omitempty" gorm:"foreignKey:TeamID"`
 }
 
+type TeamRating struct {
+	ID       uint    `json:"id" gorm:"primaryKey"`
+	TeamID   uint    `json:"team_id"`
+	LeagueID uint    `json:"league_id"`
+	Week     int     `json:"week"`
+	MatchID  *uint   `json:"match_id,omitempty"` // match that produced the rating, nil for the initial rating
+	Rating   float64 `json:"rating"`
+}
+
 type TeamStats struct {
 	TeamID       uint    `json:"team_id" gorm:"primaryKey"`
 	Points       int     `json:"points"`
@@ -29,18 +107,77 @@ type TeamStats struct {
 	GoalsAgainst int     `json:"goals_against"`
 	GoalDiff     int     `json:"goal_diff"`
 	Estimation   float32 `json:"estimation"`
+
+	AwayGoalsFor   int `json:"away_goals_for"`
+	FairPlayPoints int `json:"fair_play_points"` // disciplinary points, 1 per yellow card and 3 per red card
+
+	EstimationIterations int     `json:"estimation_iterations"`
+	EstimationStdErr     float32 `json:"estimation_std_err"`
 }
 
 type Match struct {
-	ID         uint  `json:"id" gorm:"primaryKey"`
-	LeagueID   uint  `json:"league_id"`
-	Week       int   `json:"week"`
-	Played     bool  `json:"played"`
-	HomeTeamID uint  `json:"home_team"`
-	AwayTeamID uint  `json:"away_team"`
-	HomeScore  int   `json:"home_score"`
-	AwayScore  int   `json:"away_score"`
-	Result     *uint `json:"result,omitempty"` // ID of winning team or nil for draw
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	LeagueID   uint         `json:"league_id"`
+	Week       int          `json:"week"`
+	Played     bool         `json:"played"`
+	HomeTeamID uint         `json:"home_team"`
+	AwayTeamID uint         `json:"away_team"`
+	HomeScore  int          `json:"home_score"`
+	AwayScore  int          `json:"away_score"`
+	Result     *uint        `json:"result,omitempty"` // ID of winning team or nil for draw
+	Seed       int64        `json:"seed"`             // derived from the league seed, drives the simulation of the match
+	Events     []MatchEvent `json:"events,omitempty" gorm:"serializer:json;type:jsonb"`
+
+	HomePenalties *int `json:"home_penalties,omitempty"` // set when the match was decided by a penalty shootout
+	AwayPenalties *int `json:"away_penalties,omitempty"`
+
+	TieID     *uint `json:"tie_id,omitempty"` // knockout tie the match is a leg of
+	Leg       int   `json:"leg,omitempty"`
+	ExtraTime bool  `json:"extra_time,omitempty"` // the scores include extra time
+}
+
+// Tie is a knockout pairing of two teams over one or two legs
+type Tie struct {
+	ID         uint    `json:"id" gorm:"primaryKey"`
+	LeagueID   uint    `json:"league_id"`
+	Stage      string  `json:"stage"`
+	Round      int     `json:"round"`
+	Slot       int     `json:"slot"`                 // position in the round, the winners of slots 2i and 2i+1 meet next round
+	HomeTeamID *uint   `json:"home_team,omitempty"`  // hosts the first leg
+	AwayTeamID *uint   `json:"away_team,omitempty"`  // nil when the home team has a bye
+	WinnerID   *uint   `json:"winner_id,omitempty"`  // nil until the tie is decided
+	DecidedBy  string  `json:"decided_by,omitempty"` // how the winner was decided
+	Matches    []Match `json:"matches,omitempty" gorm:"foreignKey:TieID"`
+
+	HomeAggregate int  `json:"home_aggregate"`
+	AwayAggregate int  `json:"away_aggregate"`
+	HomePenalties *int `json:"home_penalties,omitempty"`
+	AwayPenalties *int `json:"away_penalties,omitempty"`
+}
+
+// Pyramid is a structure of divisions whose bottom teams swap places with the top teams of the
+// division below at the end of every season
+type Pyramid struct {
+	ID        uint     `json:"id" gorm:"primaryKey"`
+	Name      string   `json:"name"`
+	Swaps     int      `json:"swaps"`  // teams swapping places between neighbouring divisions
+	Season    int      `json:"season"` // season being played, counted from 1
+	Seed      int64    `json:"seed"`
+	Divisions []League `json:"divisions,omitempty" gorm:"foreignKey:PyramidID"` // divisions of the current season, top first
+}
+
+// Bye records the team sitting out a week of a league with an odd number of teams
+type Bye struct {
+	ID       uint `json:"id" gorm:"primaryKey"`
+	LeagueID uint `json:"league_id"`
+	Week     int  `json:"week"`
+	TeamID   uint `json:"team_id"`
+}
+
+type MatchEvent struct {
+	Minute int    `json:"minute"`
+	TeamID uint   `json:"team_id"`
+	Type   string `json:"type"`
 }
 
 type WeeklyLog struct {
@@ -49,3 +186,38 @@ type WeeklyLog struct {
 	Week          int    `json:"week"`
 	TeamStatsJSON string `json:"team_stats_json" gorm:"type:jsonb"` // JSON snapshot of team stats
 }
+
+// LeagueAudit records a change rewriting the history of a league
+type LeagueAudit struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	LeagueID  uint      `json:"league_id"`
+	Action    string    `json:"action"`
+	FromWeek  int       `json:"from_week"`          // current week before the change
+	ToWeek    int       `json:"to_week"`            // current week after the change
+	MatchID   *uint     `json:"match_id,omitempty"` // match whose result was changed
+	Seed      *int64    `json:"seed,omitempty"`     // new seed of the league after a rewind
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Projection struct {
+	ID             uint   `json:"id" gorm:"primaryKey"`
+	LeagueID       uint   `json:"league_id"`
+	Week           int    `json:"week"`
+	Iterations     int    `json:"iterations"`
+	ProjectionJSON string `json:"projection_json" gorm:"type:jsonb"` // JSON snapshot of the team projections
+}
+
+// Tournament is a group stage of round robin leagues followed by a knockout stage between the
+// best teams of every group
+type Tournament struct {
+	ID           uint     `json:"id" gorm:"primaryKey"`
+	Name         string   `json:"name"`
+	GroupCount   int      `json:"group_count"`
+	Qualifiers   int      `json:"qualifiers"` // teams advancing from every group
+	Seeded       bool     `json:"seeded"`     // groups are drawn from pots of strength
+	Seed         int64    `json:"seed"`
+	KnockoutLegs int      `json:"knockout_legs"`
+	AwayGoals    bool     `json:"away_goals"`
+	KnockoutID   *uint    `json:"knockout_id,omitempty"` // knockout stage, drawn once every group is played
+	Groups       []League `json:"groups,omitempty" gorm:"foreignKey:TournamentID"`
+}
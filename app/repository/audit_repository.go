@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"fmt"
+	"insider-case/app/database"
+	"insider-case/app/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuditRewind       = "rewind"
+	AuditUndoWeek     = "undo_week"
+	AuditEditResult   = "edit_result"
+	AuditRebuildStats = "rebuild_stats"
+)
+
+type IAuditRepository interface {
+	GetAuditTrail(leagueID uint) ([]models.LeagueAudit, error)
+}
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+var _ IAuditRepository = &AuditRepository{}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{
+		db: database.GetDB(),
+	}
+}
+
+// GetAuditTrail returns the changes rewriting the history of the league, oldest first
+func (r *AuditRepository) GetAuditTrail(leagueID uint) ([]models.LeagueAudit, error) {
+	var audits []models.LeagueAudit
+	if err := r.db.Where("league_id = ?", leagueID).Order("id").Find(&audits).Error; err != nil {
+		return nil, fmt.Errorf("failed to get audit trail of league %d: %w", leagueID, err)
+	}
+	return audits, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"fmt"
+	"insider-case/app/database"
+	"insider-case/app/dto"
+
+	"gorm.io/gorm"
+)
+
+type IClubRepository interface {
+	GetAllTimeStats(competitionID uint) ([]dto.AllTimeEntry, error)
+}
+
+type ClubRepository struct {
+	db *gorm.DB
+}
+
+var _ IClubRepository = &ClubRepository{}
+
+func NewClubRepository() *ClubRepository {
+	return &ClubRepository{
+		db: database.GetDB(),
+	}
+}
+
+// GetAllTimeStats sums the team stats of every club over the seasons of the competition whose
+// first season has the given ID. Titles and positions are left to the caller.
+func (r *ClubRepository) GetAllTimeStats(competitionID uint) ([]dto.AllTimeEntry, error) {
+	var entries []dto.AllTimeEntry
+	if err := r.db.Table("teams").
+		Select(`teams.club_id, clubs.name AS club_name, COUNT(*) AS seasons,
+			SUM(team_stats.played) AS played, SUM(team_stats.won) AS won, SUM(team_stats.draw) AS draw,
+			SUM(team_stats.lost) AS lost, SUM(team_stats.goals_for) AS goals_for,
+			SUM(team_stats.goals_against) AS goals_against, SUM(team_stats.goal_diff) AS goal_diff,
+			SUM(team_stats.points) AS points`).
+		Joins("JOIN clubs ON clubs.id = teams.club_id").
+		Joins("JOIN team_stats ON team_stats.team_id = teams.id").
+		Joins("JOIN leagues ON leagues.id = teams.league_id").
+		Where("leagues.id = ? OR leagues.competition_id = ?", competitionID, competitionID).
+		Group("teams.club_id, clubs.name").
+		Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get all-time stats of competition %d: %w", competitionID, err)
+	}
+	return entries, nil
+}
@@ -1,30 +1,57 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"insider-case/app/database"
 	"insider-case/app/helpers"
 	"insider-case/app/models"
+	"insider-case/app/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrLeagueConflict is returned when another request changed the league since it was read
+var ErrLeagueConflict = errors.New("the league was changed by another request, reload it and try again")
+
 type ILeagueRepository interface {
 	CreateLeague(league *models.League) (*models.League, error)
 	GetLeagueByID(id uint) (*models.League, error)
 	InitializeLeague(league *models.League) (*models.League, error)
-	IncrementWeek(leagueID uint) (*models.League, error)
+	InitializeLeagueTx(tx *gorm.DB, league *models.League) (*models.League, error)
+	InitializeCup(league *models.League) (*models.League, error)
+	InitializeKnockout(tx *gorm.DB, league *models.League, ties []models.Tie) (*models.League, error)
+	IncrementWeek(leagueID uint, version int) (*models.League, error)
+	LockLeague(leagueID uint) (*models.League, error)
 	GetMatchesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Match, error)
 	GetRemainingMatches(leagueID uint, week int) ([]models.Match, error)
+	GetPlayedMatches(leagueID uint) ([]models.Match, error)
 	GetTeamRepository() ITeamRepository
 	GetTeamsByLeagueID(leagueID uint) ([]models.Team, error)
+	GetCompetitionSeasons(competitionID uint) ([]models.League, error)
+	GetLeagues() ([]models.League, error)
+	RewindLeague(league models.League, week int, stats []models.TeamStats, action string, seed *int64) error
+	RewriteResult(league models.League, rewrite ResultRewrite) error
+	RebuildStats(league models.League, stats []models.TeamStats) error
+}
+
+// ResultRewrite is the history of a league recomputed after the result of a played match changed
+type ResultRewrite struct {
+	Match       models.Match         // the match with its new result
+	Stats       []models.TeamStats   // current team stats
+	Ratings     []models.TeamRating  // ratings produced by every played match
+	TeamRatings map[uint]float64     // current rating of every team
+	WeeklyStats [][]models.TeamStats // team stats at the end of every week from the week of the match on
 }
 
 type LeagueRepository struct {
-	db                  *gorm.DB
-	teamRepository      ITeamRepository
-	matchRepository     IMatchRepository
-	teamStatsRepository ITeamStatsRepository
+	db                   *gorm.DB
+	teamRepository       ITeamRepository
+	matchRepository      IMatchRepository
+	teamStatsRepository  ITeamStatsRepository
+	teamRatingRepository ITeamRatingRepository
+	tieRepository        ITieRepository
 }
 
 var _ ILeagueRepository = &LeagueRepository{}
@@ -33,12 +60,16 @@ func NewLeagueRepository(
 	TeamRepo ITeamRepository,
 	MatchRepo IMatchRepository,
 	TeamStatsRepo ITeamStatsRepository,
+	TeamRatingRepo ITeamRatingRepository,
+	TieRepo ITieRepository,
 ) *LeagueRepository {
 	return &LeagueRepository{
-		db:                  database.GetDB(),
-		teamRepository:      TeamRepo,
-		matchRepository:     MatchRepo,
-		teamStatsRepository: TeamStatsRepo}
+		db:                   database.GetDB(),
+		teamRepository:       TeamRepo,
+		matchRepository:      MatchRepo,
+		teamStatsRepository:  TeamStatsRepo,
+		teamRatingRepository: TeamRatingRepo,
+		tieRepository:        TieRepo}
 }
 
 func (r *LeagueRepository) CreateLeague(league *models.League) (*models.League, error) {
@@ -59,6 +90,24 @@ func (r *LeagueRepository) GetLeagueByID(id uint) (*models.League, error) {
 }
 
 func (r *LeagueRepository) InitializeLeague(league *models.League) (*models.League, error) {
+	var createdLeague *models.League
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		createdLeague, err = r.InitializeLeagueTx(tx, league)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("League created successfully: id=%d, name=%s, teams=%d\n",
+		createdLeague.ID, createdLeague.Name, len(createdLeague.Teams))
+
+	return createdLeague, nil
+}
+
+// InitializeLeagueTx creates the league, its teams and its fixtures within the given transaction
+func (r *LeagueRepository) InitializeLeagueTx(tx *gorm.DB, league *models.League) (*models.League, error) {
 	// Validate league basic requirements
 	if league.Name == "" {
 		return nil, fmt.Errorf("league name is required")
@@ -67,79 +116,172 @@ func (r *LeagueRepository) InitializeLeague(league *models.League) (*models.Leag
 		return nil, fmt.Errorf("team count must be greater than 0")
 	}
 
-	// Validate teams before starting transaction
+	if err := r.teamRepository.ValidateTeams(league.Teams, league.TeamCount); err != nil {
+		return nil, fmt.Errorf("team validation failed: %w", err)
+	}
+
+	maxWeeks := helpers.CalculateMaxWeeks(league.TeamCount, league.Legs)
+	if league.Format == utils.FormatSwiss {
+		maxWeeks = league.MaxWeeks // the rounds configured, paired as the league goes
+	}
+	leagueToCreate, err := r.createLeagueWithTeams(tx, league, maxWeeks)
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures, byes, err := r.matchRepository.GenerateFixtures(*leagueToCreate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate fixtures: %w", err)
+	}
+	// Create all matches
+	if err := tx.Create(&fixtures).Error; err != nil {
+		return nil, fmt.Errorf("failed to create fixtures: %w", err)
+	}
+	if len(byes) > 0 {
+		if err := tx.Create(&byes).Error; err != nil {
+			return nil, fmt.Errorf("failed to create byes: %w", err)
+		}
+	}
+
+	// Load the complete league with teams and matches
+	var createdLeague *models.League
+	if err := tx.Preload("Teams").Preload("Teams.Stats").Preload("Matches").Preload("Byes").First(&createdLeague, leagueToCreate.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load created league: %w", err)
+	}
+	return createdLeague, nil
+}
+
+// InitializeKnockout creates the knockout stage of a tournament within the given transaction. Its
+// ties are played by the teams of the groups, so the stage has no teams of its own.
+func (r *LeagueRepository) InitializeKnockout(tx *gorm.DB, league *models.League, ties []models.Tie) (*models.League, error) {
+	league.CurrWeek = 1
+	league.MaxWeeks = utils.KnockoutRounds(league.TeamCount) * utils.NewKnockoutRules(*league).Legs
+	if err := tx.Create(league).Error; err != nil {
+		return nil, fmt.Errorf("failed to create knockout stage: %w", err)
+	}
+	if _, err := r.tieRepository.InitializeBracket(tx, *league, utils.NewKnockoutRules(*league), ties, 1); err != nil {
+		return nil, fmt.Errorf("failed to draw the bracket: %w", err)
+	}
+	return league, nil
+}
+
+// createLeagueWithTeams creates the league, its teams and their stats and ratings within the transaction
+func (r *LeagueRepository) createLeagueWithTeams(tx *gorm.DB, league *models.League, maxWeeks int) (*models.League, error) {
+	leagueToCreate := &models.League{
+		Name:         league.Name,
+		TeamCount:    league.TeamCount,
+		MaxWeeks:     maxWeeks,
+		Legs:         league.Legs,
+		CurrWeek:     1,
+		Engine:       league.Engine,
+		EngineParams: league.EngineParams,
+		UseRating:    league.UseRating,
+		Seed:         league.Seed,
+
+		SimulationIterations: league.SimulationIterations,
+		TargetStdErr:         league.TargetStdErr,
+		EstimationStartWeek:  league.EstimationStartWeek,
+		TournamentID:         league.TournamentID,
+		PyramidID:            league.PyramidID,
+		CompetitionID:        league.CompetitionID,
+		Season:               league.Season,
+		Division:             league.Division,
+		Promoted:             league.Promoted,
+		Relegated:            league.Relegated,
+		TieBreakers:          league.TieBreakers,
+		Points:               league.Points,
+		Playoffs:             league.Playoffs,
+		Format:               league.Format,
+		AwayGoals:            league.AwayGoals,
+		Seeded:               league.Seeded}
+
+	if err := tx.Create(leagueToCreate).Error; err != nil {
+		return nil, fmt.Errorf("failed to create league: %w", err)
+	}
+	leagueToCreate.Teams = league.Teams
+	// Create teams with the new league ID
+	if err := r.teamRepository.CreateTeams(tx, leagueToCreate.Teams, leagueToCreate.ID); err != nil {
+		return nil, err
+	}
+	// Initialize team stats
+	if err := r.teamStatsRepository.InitializeTeamStats(tx, leagueToCreate.Teams); err != nil {
+		return nil, fmt.Errorf("failed to initialize team stats: %w", err)
+	}
+	if err := r.teamRatingRepository.InitializeTeamRatings(tx, leagueToCreate.Teams); err != nil {
+		return nil, err
+	}
+	return leagueToCreate, nil
+}
+
+// InitializeCup creates a knockout competition, its teams and the ties of the first round
+func (r *LeagueRepository) InitializeCup(league *models.League) (*models.League, error) {
+	if league.Name == "" {
+		return nil, fmt.Errorf("cup name is required")
+	}
+	if league.TeamCount < 2 {
+		return nil, fmt.Errorf("a cup needs at least 2 teams")
+	}
 	if err := r.teamRepository.ValidateTeams(league.Teams, league.TeamCount); err != nil {
 		return nil, fmt.Errorf("team validation failed: %w", err)
 	}
 
 	var createdLeague *models.League
 	err := r.db.Transaction(func(tx *gorm.DB) error {
-		// Create league first (without teams)
-		leagueToCreate := &models.League{
-			Name:      league.Name,
-			TeamCount: league.TeamCount,
-			MaxWeeks:  helpers.CalculateMaxWeeks(league.TeamCount),
-			CurrWeek:  1}
-
-		if err := tx.Create(leagueToCreate).Error; err != nil {
-			return fmt.Errorf("failed to create league: %w", err)
-		}
-		leagueToCreate.Teams = league.Teams
-		// Create teams with the new league ID
-		if err := r.teamRepository.CreateTeams(tx, leagueToCreate.Teams, leagueToCreate.ID); err != nil {
+		maxWeeks := utils.KnockoutRounds(league.TeamCount) * utils.NewKnockoutRules(*league).Legs
+		leagueToCreate, err := r.createLeagueWithTeams(tx, league, maxWeeks)
+		if err != nil {
 			return err
 		}
-		// Initialize team stats
-		if err := r.teamStatsRepository.InitializeTeamStats(tx, leagueToCreate.Teams); err != nil {
-			return fmt.Errorf("failed to initialize team stats: %w", err)
-		}
 
-		fixtures, err := r.matchRepository.GenerateFixtures(*leagueToCreate)
-		if err != nil {
-			return fmt.Errorf("failed to generate fixtures: %w", err)
-		}
-		// Create all matches
-		if err := tx.Create(&fixtures).Error; err != nil {
-			return fmt.Errorf("failed to create fixtures: %w", err)
+		ties := utils.SeedBracket(leagueToCreate.Teams, leagueToCreate.Seeded, leagueToCreate.Seed)
+		if _, err := r.tieRepository.InitializeBracket(tx, *leagueToCreate, utils.NewKnockoutRules(*leagueToCreate), ties, 1); err != nil {
+			return fmt.Errorf("failed to draw the bracket: %w", err)
 		}
 
-		// Load the complete league with teams and matches
-		if err := tx.Preload("Teams").Preload("Teams.Stats").Preload("Matches").First(&createdLeague, leagueToCreate.ID).Error; err != nil {
-			return fmt.Errorf("failed to load created league: %w", err)
+		if err := tx.Preload("Teams").First(&createdLeague, leagueToCreate.ID).Error; err != nil {
+			return fmt.Errorf("failed to load created cup: %w", err)
 		}
-
 		return nil
-
 	})
-
 	if err != nil {
 		return nil, err
 	}
 
-	fmt.Printf("League created successfully: id=%d, name=%s, teams=%d\n",
+	fmt.Printf("Cup created successfully: id=%d, name=%s, teams=%d\n",
 		createdLeague.ID, createdLeague.Name, len(createdLeague.Teams))
 
 	return createdLeague, nil
 }
-func (r *LeagueRepository) IncrementWeek(leagueID uint) (*models.League, error) {
+
+// IncrementWeek moves the league to its next week provided it is still at the given version
+func (r *LeagueRepository) IncrementWeek(leagueID uint, version int) (*models.League, error) {
+	if err := updateLeague(r.db, leagueID, version, map[string]interface{}{
+		"curr_week": gorm.Expr("curr_week + 1"),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to increment week for league %d: %w", leagueID, err)
+	}
 	var league models.League
 	if err := r.db.First(&league, leagueID).Error; err != nil {
 		return nil, fmt.Errorf("failed to find league with ID %d: %w", leagueID, err)
 	}
 
-	// Increment the current week
-	league.CurrWeek++
-	if err := r.db.Save(&league).Error; err != nil {
-		return nil, fmt.Errorf("failed to increment week for league %d: %w", leagueID, err)
-	}
-
 	fmt.Printf("League week incremented: id=%d, new week=%d\n", league.ID, league.CurrWeek)
 
 	return &league, nil
 }
+
+// LockLeague reads the league and locks its row until the end of the transaction
+func (r *LeagueRepository) LockLeague(leagueID uint) (*models.League, error) {
+	var league models.League
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&league, leagueID).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock league %d: %w", leagueID, err)
+	}
+	return &league, nil
+}
+
 func (r *LeagueRepository) GetMatchesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Match, error) {
 	var matches []models.Match
-	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).Find(&matches).Error; err != nil {
+	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).Order("id").Find(&matches).Error; err != nil {
 		return nil, fmt.Errorf("failed to get matches for league %d and week %d: %w", leagueID, week, err)
 	}
 	return matches, nil
@@ -147,20 +289,271 @@ func (r *LeagueRepository) GetMatchesByLeagueIdAndWeek(leagueID uint, week int)
 
 func (r *LeagueRepository) GetRemainingMatches(leagueID uint, week int) ([]models.Match, error) {
 	var matches []models.Match
-	if err := r.db.Where("league_id = ? AND week > ?", leagueID, week).Find(&matches).Error; err != nil {
+	if err := r.db.Where("league_id = ? AND week > ? AND tie_id IS NULL", leagueID, week).Order("week, id").Find(&matches).Error; err != nil {
 		return nil, fmt.Errorf("failed to get remaining matches for league %d: %w", leagueID, err)
 	}
 	return matches, nil
 }
 
+func (r *LeagueRepository) GetPlayedMatches(leagueID uint) ([]models.Match, error) {
+	var matches []models.Match
+	if err := r.db.Where("league_id = ? AND played AND tie_id IS NULL", leagueID).Order("week, id").Find(&matches).Error; err != nil {
+		return nil, fmt.Errorf("failed to get played matches for league %d: %w", leagueID, err)
+	}
+	return matches, nil
+}
+
 func (r *LeagueRepository) GetTeamRepository() ITeamRepository {
 	return r.teamRepository
 }
 
 func (r *LeagueRepository) GetTeamsByLeagueID(leagueID uint) ([]models.Team, error) {
 	var teams []models.Team
-	if err := r.db.Where("league_id = ?", leagueID).Find(&teams).Error; err != nil {
+	if err := r.db.Where("league_id = ?", leagueID).Order("id").Find(&teams).Error; err != nil {
 		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
 	}
 	return teams, nil
 }
+
+// GetCompetitionSeasons returns every season of the competition whose first season has the given ID
+func (r *LeagueRepository) GetCompetitionSeasons(competitionID uint) ([]models.League, error) {
+	var seasons []models.League
+	if err := r.db.Where("id = ? OR competition_id = ?", competitionID, competitionID).
+		Order("season, id").Find(&seasons).Error; err != nil {
+		return nil, fmt.Errorf("failed to get seasons of competition %d: %w", competitionID, err)
+	}
+	return seasons, nil
+}
+
+// GetLeagues returns every league, cups, groups and divisions included
+func (r *LeagueRepository) GetLeagues() ([]models.League, error) {
+	var leagues []models.League
+	if err := r.db.Order("id").Find(&leagues).Error; err != nil {
+		return nil, fmt.Errorf("failed to get leagues: %w", err)
+	}
+	return leagues, nil
+}
+
+// RewindLeague takes the league back to the end of the given week in a single transaction. The
+// matches after the week are reset to unplayed, the team stats replaced by the given ones and the
+// ratings, weekly logs and projections of the later weeks deleted. Playoff ties are deleted as
+// they are drawn once the regular season is over, and so are the rounds a Swiss-system league
+// paired after the round following the week. A non-nil seed replaces the seed of the league and
+// the reset matches are seeded from it again. The rewind is recorded in the audit trail under the
+// given action, with the new seed.
+func (r *LeagueRepository) RewindLeague(league models.League, week int, stats []models.TeamStats, action string, seed *int64) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("league_id = ? AND tie_id IS NOT NULL", league.ID).Delete(&models.Match{}).Error; err != nil {
+			return fmt.Errorf("failed to delete playoff matches: %w", err)
+		}
+		if err := tx.Where("league_id = ?", league.ID).Delete(&models.Tie{}).Error; err != nil {
+			return fmt.Errorf("failed to delete playoff ties: %w", err)
+		}
+		if league.Format == utils.FormatSwiss {
+			if err := tx.Where("league_id = ? AND week > ?", league.ID, week+1).Delete(&models.Match{}).Error; err != nil {
+				return fmt.Errorf("failed to delete paired rounds: %w", err)
+			}
+			if err := tx.Where("league_id = ? AND week > ?", league.ID, week+1).Delete(&models.Bye{}).Error; err != nil {
+				return fmt.Errorf("failed to delete byes of paired rounds: %w", err)
+			}
+		}
+		if err := tx.Model(&models.Match{}).Where("league_id = ? AND week > ?", league.ID, week).
+			Updates(map[string]interface{}{
+				"played":         false,
+				"home_score":     0,
+				"away_score":     0,
+				"result":         nil,
+				"events":         nil,
+				"home_penalties": nil,
+				"away_penalties": nil,
+				"extra_time":     false,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to reset matches after week %d: %w", week, err)
+		}
+		if seed != nil {
+			if err := reseedMatches(tx, league.ID, week, *seed); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("league_id = ? AND week > ?", league.ID, week).Delete(&models.TeamRating{}).Error; err != nil {
+			return fmt.Errorf("failed to delete ratings after week %d: %w", week, err)
+		}
+		if err := tx.Exec(`UPDATE teams SET rating = (
+				SELECT team_ratings.rating FROM team_ratings
+				WHERE team_ratings.team_id = teams.id
+				ORDER BY team_ratings.week DESC, team_ratings.id DESC LIMIT 1
+			) WHERE league_id = ?`, league.ID).Error; err != nil {
+			return fmt.Errorf("failed to restore ratings of week %d: %w", week, err)
+		}
+		for _, stat := range stats {
+			if err := tx.Save(&stat).Error; err != nil {
+				return fmt.Errorf("failed to restore stats of team %d: %w", stat.TeamID, err)
+			}
+		}
+
+		if err := tx.Where("league_id = ? AND week > ?", league.ID, week).Delete(&models.WeeklyLog{}).Error; err != nil {
+			return fmt.Errorf("failed to delete weekly logs after week %d: %w", week, err)
+		}
+		if err := tx.Where("league_id = ? AND week > ?", league.ID, week).Delete(&models.Projection{}).Error; err != nil {
+			return fmt.Errorf("failed to delete projections after week %d: %w", week, err)
+		}
+
+		updates := map[string]interface{}{"curr_week": week + 1}
+		if seed != nil {
+			updates["seed"] = *seed
+		}
+		if err := updateLeague(tx, league.ID, league.Version, updates); err != nil {
+			return fmt.Errorf("failed to reset the week of league %d: %w", league.ID, err)
+		}
+		if err := tx.Create(&models.LeagueAudit{
+			LeagueID: league.ID,
+			Action:   action,
+			FromWeek: league.CurrWeek,
+			ToWeek:   week + 1,
+			Seed:     seed,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record the %s of league %d: %w", action, league.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("League rewound: id=%d, week=%d\n", league.ID, week)
+	return nil
+}
+
+// reseedMatches derives the seeds of the league matches after the week from the given seed, the
+// way the fixtures are seeded when they are scheduled
+func reseedMatches(tx *gorm.DB, leagueID uint, week int, seed int64) error {
+	var matches []models.Match
+	if err := tx.Where("league_id = ? AND week > ? AND tie_id IS NULL", leagueID, week).Order("week, id").Find(&matches).Error; err != nil {
+		return fmt.Errorf("failed to get matches after week %d: %w", week, err)
+	}
+	j := 0
+	for i, match := range matches {
+		if i > 0 && match.Week != matches[i-1].Week {
+			j = 0
+		}
+		if err := tx.Model(&models.Match{}).Where("id = ?", match.ID).
+			Update("seed", utils.DeriveSeed(seed, int64(match.Week), int64(j))).Error; err != nil {
+			return fmt.Errorf("failed to reseed match %d: %w", match.ID, err)
+		}
+		j++
+	}
+	return nil
+}
+
+// RewriteResult writes back the history of the league recomputed after the result of a played
+// match changed in a single transaction, replacing the team stats, the rating history and the
+// weekly logs of the weeks from the week of the match on. Their projections are deleted until they
+// are estimated again. The change is recorded in the audit trail.
+func (r *LeagueRepository) RewriteResult(league models.League, rewrite ResultRewrite) error {
+	week := rewrite.Match.Week
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&rewrite.Match).Error; err != nil {
+			return fmt.Errorf("failed to update match %d: %w", rewrite.Match.ID, err)
+		}
+		for _, stat := range rewrite.Stats {
+			if err := tx.Save(&stat).Error; err != nil {
+				return fmt.Errorf("failed to update stats of team %d: %w", stat.TeamID, err)
+			}
+		}
+
+		if err := tx.Where("league_id = ? AND match_id IS NOT NULL", league.ID).Delete(&models.TeamRating{}).Error; err != nil {
+			return fmt.Errorf("failed to delete rating history: %w", err)
+		}
+		if len(rewrite.Ratings) > 0 {
+			if err := tx.Create(&rewrite.Ratings).Error; err != nil {
+				return fmt.Errorf("failed to save rating history: %w", err)
+			}
+		}
+		for teamID, rating := range rewrite.TeamRatings {
+			if err := tx.Model(&models.Team{}).Where("id = ?", teamID).Update("rating", rating).Error; err != nil {
+				return fmt.Errorf("failed to update rating of team %d: %w", teamID, err)
+			}
+		}
+
+		if err := tx.Where("league_id = ? AND week >= ?", league.ID, week).Delete(&models.WeeklyLog{}).Error; err != nil {
+			return fmt.Errorf("failed to delete weekly logs from week %d: %w", week, err)
+		}
+		for i, stats := range rewrite.WeeklyStats {
+			log, err := newWeeklyLog(league.ID, week+i, stats)
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(&log).Error; err != nil {
+				return fmt.Errorf("failed to save weekly log of week %d: %w", week+i, err)
+			}
+		}
+		if err := tx.Where("league_id = ? AND week >= ?", league.ID, week).Delete(&models.Projection{}).Error; err != nil {
+			return fmt.Errorf("failed to delete projections from week %d: %w", week, err)
+		}
+
+		if err := updateLeague(tx, league.ID, league.Version, map[string]interface{}{}); err != nil {
+			return fmt.Errorf("failed to update league %d: %w", league.ID, err)
+		}
+		if err := tx.Create(&models.LeagueAudit{
+			LeagueID: league.ID,
+			Action:   AuditEditResult,
+			FromWeek: league.CurrWeek,
+			ToWeek:   league.CurrWeek,
+			MatchID:  &rewrite.Match.ID,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record the %s of league %d: %w", AuditEditResult, league.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Match result rewritten: league=%d, match=%d, week=%d\n", league.ID, rewrite.Match.ID, week)
+	return nil
+}
+
+// RebuildStats replaces the team stats of the league with the stats rebuilt from its matches in a
+// single transaction and records the rebuild in the audit trail
+func (r *LeagueRepository) RebuildStats(league models.League, stats []models.TeamStats) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, stat := range stats {
+			if err := tx.Save(&stat).Error; err != nil {
+				return fmt.Errorf("failed to save stats of team %d: %w", stat.TeamID, err)
+			}
+		}
+		if err := updateLeague(tx, league.ID, league.Version, map[string]interface{}{}); err != nil {
+			return fmt.Errorf("failed to update league %d: %w", league.ID, err)
+		}
+		if err := tx.Create(&models.LeagueAudit{
+			LeagueID: league.ID,
+			Action:   AuditRebuildStats,
+			FromWeek: league.CurrWeek,
+			ToWeek:   league.CurrWeek,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record the %s of league %d: %w", AuditRebuildStats, league.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Team stats rebuilt: league=%d, teams=%d\n", league.ID, len(stats))
+	return nil
+}
+
+// updateLeague applies the updates to the league and bumps its version. The update only goes
+// through while the league is still at the given version, ErrLeagueConflict otherwise.
+func updateLeague(db *gorm.DB, leagueID uint, version int, updates map[string]interface{}) error {
+	updates["version"] = gorm.Expr("version + 1")
+	result := db.Model(&models.League{}).Where("id = ? AND version = ?", leagueID, version).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeagueConflict
+	}
+	return nil
+}
@@ -4,18 +4,19 @@ import (
 	"fmt"
 	"insider-case/app/database"
 	"insider-case/app/models"
-	"math/rand"
-	"time"
+	"insider-case/app/utils"
 
 	"gorm.io/gorm"
 )
 
 type IMatchRepository interface {
-	GenerateFixtures(league models.League) ([]models.Match, error)
+	GenerateFixtures(league models.League) ([]models.Match, []models.Bye, error)
 	GetMatchesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Match, error)
 	GetMatchesByLeagueId(leagueID uint) ([]models.Match, error)
 	SaveMatch(match models.Match) error
 	GetMatchByID(matchID uint) (*models.Match, error)
+	GetByesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Bye, error)
+	ScheduleSwissRound(league models.League, week int, ranked []uint) ([]models.Match, []models.Bye, error)
 }
 
 type MatchRepository struct {
@@ -29,107 +30,116 @@ func NewMatchRepository() *MatchRepository {
 		db: database.GetDB()}
 }
 
-func (r *MatchRepository) GenerateFixtures(league models.League) ([]models.Match, error) {
-	if len(league.Teams)%2 != 0 {
-		return nil, fmt.Errorf("number of teams must be even")
+// GenerateFixtures schedules a round robin of the league's legs between its teams and returns the
+// byes of the weeks a team sits out. The teams are shuffled with the league seed before being
+// placed on the schedule so every seed gets its own fixture list. A Swiss-system league only gets
+// its first round, paired in the shuffled order, as later rounds depend on the results.
+func (r *MatchRepository) GenerateFixtures(league models.League) ([]models.Match, []models.Bye, error) {
+	rng := utils.NewRand(league.Seed)
+	teams := make([]models.Team, len(league.Teams))
+	copy(teams, league.Teams)
+	rng.Shuffle(len(teams), func(i, j int) {
+		teams[i], teams[j] = teams[j], teams[i]
+	})
+
+	if league.Format == utils.FormatSwiss {
+		teamIDs := make([]uint, len(teams))
+		for i, team := range teams {
+			teamIDs[i] = team.ID
+		}
+		matches, byes := swissRound(league, 1, teamIDs, nil)
+		return matches, byes, nil
 	}
 
-	numTeams := len(league.Teams)
-	maxWeeks := league.MaxWeeks
-	halfSeason := maxWeeks / 2
-	matchesPerWeek := numTeams / 2
-
-	// Track played pairs using string keys
-	played := make(map[string]bool)
-
-	matches := make([]models.Match, 0)
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-
-	// First half of the season
-	for week := 1; week <= halfSeason; week++ {
-		usedTeams := make(map[string]bool)
-		weekMatches := make([]models.Match, 0, matchesPerWeek)
-
-		attempts := 0
-		for len(weekMatches) < matchesPerWeek && attempts < 1000 {
-			attempts++
-
-			i := rand.Intn(numTeams)
-			j := rand.Intn(numTeams)
-			if i == j {
-				continue
-			}
-
-			teamA := league.Teams[i]
-			teamB := league.Teams[j]
-
-			keyA := teamA.Name
-			keyB := teamB.Name
-
-			if usedTeams[keyA] || usedTeams[keyB] {
-				continue
-			}
-
-			matchKey := getMatchKey(keyA, keyB)
-			if played[matchKey] {
-				continue
-			}
-
-			// Add match
-			usedTeams[keyA] = true
-			usedTeams[keyB] = true
-			played[matchKey] = true
+	rounds := utils.RoundRobin(len(teams), league.Legs)
+	if len(rounds) != league.MaxWeeks {
+		return nil, nil, fmt.Errorf("schedule has %d rounds but league %d has %d weeks", len(rounds), league.ID, league.MaxWeeks)
+	}
 
-			match := models.Match{
+	matches := make([]models.Match, 0, len(rounds)*len(teams)/2)
+	var byes []models.Bye
+	for i, round := range rounds {
+		week := i + 1
+		if round.Bye != nil {
+			byes = append(byes, models.Bye{
+				LeagueID: league.ID,
+				Week:     week,
+				TeamID:   teams[*round.Bye].ID,
+			})
+		}
+		for j, pairing := range round.Pairings {
+			matches = append(matches, models.Match{
 				LeagueID:   league.ID,
 				Week:       week,
-				HomeTeamID: teamA.ID,
-				AwayTeamID: teamB.ID,
-			}
-			weekMatches = append(weekMatches, match)
+				HomeTeamID: teams[pairing.Home].ID,
+				AwayTeamID: teams[pairing.Away].ID,
+				Seed:       utils.DeriveSeed(league.Seed, int64(week), int64(j)),
+			})
 		}
+	}
 
-		if len(weekMatches) < matchesPerWeek {
-			return nil, fmt.Errorf("could not generate valid fixtures after many attempts")
-		}
+	return matches, byes, nil
+}
 
-		matches = append(matches, weekMatches...)
+// ScheduleSwissRound pairs the given week of a Swiss-system league from the team IDs ordered by
+// the current standings, avoiding rematches of the weeks paired before, and creates its matches and byes
+func (r *MatchRepository) ScheduleSwissRound(league models.League, week int, ranked []uint) ([]models.Match, []models.Bye, error) {
+	var previous []models.Match
+	if err := r.db.Where("league_id = ? AND week < ? AND tie_id IS NULL", league.ID, week).Find(&previous).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to get previous rounds of league %d: %w", league.ID, err)
 	}
-
-	// Second half of the season (reverse matches)
-	totalFirstHalf := len(matches)
-	for i := 0; i < totalFirstHalf; i++ {
-		original := matches[i]
-		reverse := models.Match{
-			LeagueID:   original.LeagueID,
-			Week:       original.Week + halfSeason,
-			HomeTeamID: original.AwayTeamID,
-			AwayTeamID: original.HomeTeamID,
+	matches, byes := swissRound(league, week, ranked, previous)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&matches).Error; err != nil {
+			return fmt.Errorf("failed to create matches of week %d: %w", week, err)
+		}
+		if len(byes) > 0 {
+			if err := tx.Create(&byes).Error; err != nil {
+				return fmt.Errorf("failed to create byes of week %d: %w", week, err)
+			}
 		}
-		matches = append(matches, reverse)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-
-	return matches, nil
+	return matches, byes, nil
 }
 
-// getMatchKey returns a consistent key for a match regardless of team order
-func getMatchKey(teamA, teamB string) string {
-	if teamA < teamB {
-		return teamA + "-" + teamB
+// swissRound returns the matches and the bye of a round of a Swiss-system league
+func swissRound(league models.League, week int, ranked []uint, previous []models.Match) ([]models.Match, []models.Bye) {
+	round := utils.SwissPairings(ranked, previous)
+	var byes []models.Bye
+	if round.Bye != nil {
+		byes = append(byes, models.Bye{
+			LeagueID: league.ID,
+			Week:     week,
+			TeamID:   ranked[*round.Bye],
+		})
+	}
+	matches := make([]models.Match, len(round.Pairings))
+	for j, pairing := range round.Pairings {
+		matches[j] = models.Match{
+			LeagueID:   league.ID,
+			Week:       week,
+			HomeTeamID: ranked[pairing.Home],
+			AwayTeamID: ranked[pairing.Away],
+			Seed:       utils.DeriveSeed(league.Seed, int64(week), int64(j)),
+		}
 	}
-	return teamB + "-" + teamA
+	return matches, byes
 }
 
 func (r *MatchRepository) GetMatchesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Match, error) {
 	var matches []models.Match
-	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).Find(&matches).Error; err != nil {
+	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).Order("id").Find(&matches).Error; err != nil {
 		return nil, fmt.Errorf("failed to get matches for league %d and week %d: %w", leagueID, week, err)
 	}
 	return matches, nil
 }
 func (r *MatchRepository) GetMatchesByLeagueId(leagueID uint) ([]models.Match, error) {
 	var matches []models.Match
-	if err := r.db.Where("league_id = ?", leagueID).Find(&matches).Error; err != nil {
+	if err := r.db.Where("league_id = ?", leagueID).Order("week, id").Find(&matches).Error; err != nil {
 		return nil, fmt.Errorf("failed to get matches for league %d: %w", leagueID, err)
 	}
 	return matches, nil
@@ -150,6 +160,10 @@ func (r *MatchRepository) SaveMatch(match models.Match) error {
 	existingMatch.AwayScore = match.AwayScore
 	existingMatch.Played = true
 	existingMatch.Result = match.Result
+	existingMatch.Events = match.Events
+	existingMatch.HomePenalties = match.HomePenalties
+	existingMatch.AwayPenalties = match.AwayPenalties
+	existingMatch.ExtraTime = match.ExtraTime
 
 	if err := r.db.Save(&existingMatch).Error; err != nil {
 		return fmt.Errorf("failed to update match with ID %d: %w", match.ID, err)
@@ -158,6 +172,14 @@ func (r *MatchRepository) SaveMatch(match models.Match) error {
 	return nil
 }
 
+func (r *MatchRepository) GetByesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Bye, error) {
+	var byes []models.Bye
+	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).Order("id").Find(&byes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get byes for league %d and week %d: %w", leagueID, week, err)
+	}
+	return byes, nil
+}
+
 func (r *MatchRepository) GetMatchByID(matchID uint) (*models.Match, error) {
 	var match models.Match
 	if err := r.db.First(&match, matchID).Error; err != nil {
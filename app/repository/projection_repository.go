@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"insider-case/app/database"
+	"insider-case/app/dto"
+	"insider-case/app/models"
+
+	"gorm.io/gorm"
+)
+
+type IProjectionRepository interface {
+	SaveProjection(projection dto.LeagueProjection) error
+	GetLatestProjection(leagueID uint) (*dto.LeagueProjection, error)
+	GetProjectionByLeagueIDAndWeek(leagueID uint, week int) (*dto.LeagueProjection, error)
+}
+
+type ProjectionRepository struct {
+	db *gorm.DB
+}
+
+var _ IProjectionRepository = &ProjectionRepository{}
+
+func NewProjectionRepository() *ProjectionRepository {
+	return &ProjectionRepository{
+		db: database.GetDB(),
+	}
+}
+
+func (r *ProjectionRepository) SaveProjection(projection dto.LeagueProjection) error {
+	record, err := newProjectionRecord(projection)
+	if err != nil {
+		return err
+	}
+	if err := r.db.Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to save projection: %w", err)
+	}
+	return nil
+}
+
+// newProjectionRecord returns the record storing the team projections of the projection
+func newProjectionRecord(projection dto.LeagueProjection) (models.Projection, error) {
+	projectionJSON, err := json.Marshal(projection.Teams)
+	if err != nil {
+		return models.Projection{}, fmt.Errorf("failed to marshal projection to JSON: %w", err)
+	}
+	return models.Projection{
+		LeagueID:       projection.LeagueID,
+		Week:           projection.Week,
+		Iterations:     projection.Iterations,
+		ProjectionJSON: string(projectionJSON),
+	}, nil
+}
+
+func (r *ProjectionRepository) GetLatestProjection(leagueID uint) (*dto.LeagueProjection, error) {
+	var record models.Projection
+	if err := r.db.Where("league_id = ?", leagueID).Order("week DESC, id DESC").First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("no projection found for league %d", leagueID)
+		}
+		return nil, fmt.Errorf("failed to get projection: %w", err)
+	}
+	return toLeagueProjection(record)
+}
+
+func (r *ProjectionRepository) GetProjectionByLeagueIDAndWeek(leagueID uint, week int) (*dto.LeagueProjection, error) {
+	var record models.Projection
+	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).Order("id DESC").First(&record).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("no projection found for league %d and week %d", leagueID, week)
+		}
+		return nil, fmt.Errorf("failed to get projection: %w", err)
+	}
+	return toLeagueProjection(record)
+}
+
+func toLeagueProjection(record models.Projection) (*dto.LeagueProjection, error) {
+	projection := &dto.LeagueProjection{
+		LeagueID:   record.LeagueID,
+		Week:       record.Week,
+		Iterations: record.Iterations,
+	}
+	if err := json.Unmarshal([]byte(record.ProjectionJSON), &projection.Teams); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal projection: %w", err)
+	}
+	return projection, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"fmt"
+	"insider-case/app/database"
+	"insider-case/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IPyramidRepository interface {
+	InitializePyramid(pyramid *models.Pyramid, divisions []*models.League) (*models.Pyramid, error)
+	GetPyramidByID(id uint) (*models.Pyramid, error)
+	LockPyramid(id uint) (*models.Pyramid, error)
+	InitializeSeason(pyramid *models.Pyramid, divisions []*models.League) (*models.Pyramid, error)
+}
+
+type PyramidRepository struct {
+	db               *gorm.DB
+	leagueRepository ILeagueRepository
+}
+
+var _ IPyramidRepository = &PyramidRepository{}
+
+func NewPyramidRepository(LeagueRepo ILeagueRepository) *PyramidRepository {
+	return &PyramidRepository{
+		db:               database.GetDB(),
+		leagueRepository: LeagueRepo,
+	}
+}
+
+// InitializePyramid creates the pyramid and the leagues of its first season in a single transaction
+func (r *PyramidRepository) InitializePyramid(pyramid *models.Pyramid, divisions []*models.League) (*models.Pyramid, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Divisions").Create(pyramid).Error; err != nil {
+			return fmt.Errorf("failed to create pyramid: %w", err)
+		}
+		return r.createDivisions(tx, pyramid, divisions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Pyramid created successfully: id=%d, name=%s, divisions=%d\n",
+		pyramid.ID, pyramid.Name, len(divisions))
+
+	return r.GetPyramidByID(pyramid.ID)
+}
+
+// GetPyramidByID returns the pyramid with the divisions of its current season, top first
+func (r *PyramidRepository) GetPyramidByID(id uint) (*models.Pyramid, error) {
+	var pyramid models.Pyramid
+	if err := r.db.First(&pyramid, id).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("pyramid_id = ? AND season = ?", id, pyramid.Season).
+		Order("division").Find(&pyramid.Divisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get divisions of pyramid %d: %w", id, err)
+	}
+	return &pyramid, nil
+}
+
+// LockPyramid reads the pyramid with its divisions and locks its row until the end of the
+// transaction, so the weeks of the pyramid are played one request at a time
+func (r *PyramidRepository) LockPyramid(id uint) (*models.Pyramid, error) {
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Pyramid{}, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock pyramid %d: %w", id, err)
+	}
+	return r.GetPyramidByID(id)
+}
+
+// InitializeSeason moves the pyramid to its next season and creates the leagues of the season in a
+// single transaction. It fails when the season was already started by a concurrent request.
+func (r *PyramidRepository) InitializeSeason(pyramid *models.Pyramid, divisions []*models.League) (*models.Pyramid, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Pyramid{}).Where("id = ? AND season = ?", pyramid.ID, pyramid.Season).
+			Update("season", pyramid.Season+1)
+		if result.Error != nil {
+			return fmt.Errorf("failed to start season %d of pyramid %d: %w", pyramid.Season+1, pyramid.ID, result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("season %d of pyramid %d is already started", pyramid.Season+1, pyramid.ID)
+		}
+		return r.createDivisions(tx, pyramid, divisions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Pyramid season started: id=%d, season=%d\n", pyramid.ID, pyramid.Season+1)
+
+	return r.GetPyramidByID(pyramid.ID)
+}
+
+// createDivisions creates the leagues of the divisions, their teams and their fixtures within the transaction
+func (r *PyramidRepository) createDivisions(tx *gorm.DB, pyramid *models.Pyramid, divisions []*models.League) error {
+	for _, division := range divisions {
+		division.PyramidID = &pyramid.ID
+		if _, err := r.leagueRepository.InitializeLeagueTx(tx, division); err != nil {
+			return fmt.Errorf("failed to create %s: %w", division.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"fmt"
+	"insider-case/app/database"
+	"insider-case/app/models"
+
+	"gorm.io/gorm"
+)
+
+type ITeamRatingRepository interface {
+	InitializeTeamRatings(tx *gorm.DB, teams []models.Team) error
+	SaveTeamRating(rating models.TeamRating) error
+	GetRatingsByTeamID(teamID uint) ([]models.TeamRating, error)
+	GetInitialRatings(leagueID uint) (map[uint]float64, error)
+}
+
+type TeamRatingRepository struct {
+	db *gorm.DB
+}
+
+var _ ITeamRatingRepository = &TeamRatingRepository{}
+
+func NewTeamRatingRepository() *TeamRatingRepository {
+	return &TeamRatingRepository{
+		db: database.GetDB(),
+	}
+}
+
+// InitializeTeamRatings records the starting rating of each team as week 0
+func (r *TeamRatingRepository) InitializeTeamRatings(tx *gorm.DB, teams []models.Team) error {
+	ratings := make([]models.TeamRating, len(teams))
+	for i, team := range teams {
+		ratings[i] = models.TeamRating{
+			TeamID:   team.ID,
+			LeagueID: team.LeagueID,
+			Week:     0,
+			Rating:   team.Rating,
+		}
+	}
+
+	if err := tx.Create(&ratings).Error; err != nil {
+		return fmt.Errorf("failed to initialize team ratings: %w", err)
+	}
+	return nil
+}
+
+func (r *TeamRatingRepository) SaveTeamRating(rating models.TeamRating) error {
+	if err := r.db.Create(&rating).Error; err != nil {
+		return fmt.Errorf("failed to save rating for team %d: %w", rating.TeamID, err)
+	}
+	return nil
+}
+
+func (r *TeamRatingRepository) GetRatingsByTeamID(teamID uint) ([]models.TeamRating, error) {
+	var ratings []models.TeamRating
+	if err := r.db.Where("team_id = ?", teamID).Order("week, id").Find(&ratings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ratings for team %d: %w", teamID, err)
+	}
+	return ratings, nil
+}
+
+// GetInitialRatings returns the rating every team of the league started the season with
+func (r *TeamRatingRepository) GetInitialRatings(leagueID uint) (map[uint]float64, error) {
+	var ratings []models.TeamRating
+	if err := r.db.Where("league_id = ? AND match_id IS NULL", leagueID).Order("id").Find(&ratings).Error; err != nil {
+		return nil, fmt.Errorf("failed to get initial ratings for league %d: %w", leagueID, err)
+	}
+	initial := make(map[uint]float64, len(ratings))
+	for _, rating := range ratings {
+		initial[rating.TeamID] = rating.Rating
+	}
+	return initial, nil
+}
@@ -12,8 +12,10 @@ type ITeamRepository interface {
 	ValidateTeams(teams []models.Team, expectedCount int) error
 	CreateTeams(tx *gorm.DB, teams []models.Team, leagueID uint) error
 	GetTeamsByLeagueID(leagueID uint) ([]models.Team, error)
+	GetTeamsByIDs(teamIDs []uint) ([]models.Team, error)
 	GetTeamByID(TeamID uint) (models.Team, error)
 	GetTeamStrengthByID(TeamID uint) (int, error)
+	UpdateTeamRating(TeamID uint, rating float64) error
 }
 
 type TeamRepository struct {
@@ -47,9 +49,17 @@ func (r *TeamRepository) ValidateTeams(teams []models.Team, expectedCount int) e
 	return nil
 }
 
+// CreateTeams creates the teams of a league. Teams of a new club found the club, the teams of an
+// existing club carry their strength over to it.
 func (r *TeamRepository) CreateTeams(tx *gorm.DB, teams []models.Team, leagueID uint) error {
 	for i := range teams {
 		teams[i].LeagueID = leagueID
+		if teams[i].Rating == 0 {
+			teams[i].Rating = float64(teams[i].Strength) // Elo ratings start from the user supplied strength
+		}
+		if err := r.saveClub(tx, &teams[i]); err != nil {
+			return err
+		}
 	}
 
 	if err := tx.Create(&teams).Error; err != nil {
@@ -59,14 +69,38 @@ func (r *TeamRepository) CreateTeams(tx *gorm.DB, teams []models.Team, leagueID
 	return nil
 }
 
+// saveClub creates the club of a team without one and updates the strength of an existing club
+func (r *TeamRepository) saveClub(tx *gorm.DB, team *models.Team) error {
+	if team.ClubID != nil {
+		if err := tx.Model(&models.Club{}).Where("id = ?", *team.ClubID).Update("strength", team.Strength).Error; err != nil {
+			return fmt.Errorf("failed to update club %d: %w", *team.ClubID, err)
+		}
+		return nil
+	}
+	club := models.Club{Name: team.Name, Strength: team.Strength}
+	if err := tx.Create(&club).Error; err != nil {
+		return fmt.Errorf("failed to create club %s: %w", team.Name, err)
+	}
+	team.ClubID = &club.ID
+	return nil
+}
+
 func (r *TeamRepository) GetTeamsByLeagueID(leagueID uint) ([]models.Team, error) {
 	var teams []models.Team
-	if err := r.db.Where("league_id = ?", leagueID).Find(&teams).Error; err != nil {
+	if err := r.db.Where("league_id = ?", leagueID).Order("id").Find(&teams).Error; err != nil {
 		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
 	}
 	return teams, nil
 }
 
+func (r *TeamRepository) GetTeamsByIDs(teamIDs []uint) ([]models.Team, error) {
+	var teams []models.Team
+	if err := r.db.Where("id IN ?", teamIDs).Order("id").Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to get teams %v: %w", teamIDs, err)
+	}
+	return teams, nil
+}
+
 func (r *TeamRepository) GetTeamByID(TeamID uint) (models.Team, error) {
 	var team models.Team
 	if err := r.db.Where("id = ?", TeamID).First(&team).Error; err != nil {
@@ -88,3 +122,10 @@ func (r *TeamRepository) GetTeamStrengthByID(TeamID uint) (int, error) {
 	}
 	return strength, nil
 }
+
+func (r *TeamRepository) UpdateTeamRating(TeamID uint, rating float64) error {
+	if err := r.db.Model(&models.Team{}).Where("id = ?", TeamID).Update("rating", rating).Error; err != nil {
+		return fmt.Errorf("failed to update rating of team %d: %w", TeamID, err)
+	}
+	return nil
+}
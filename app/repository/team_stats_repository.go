@@ -67,7 +67,7 @@ func (r *TeamStatsRepository) GetTeamStatsByTeamID(TeamID uint) (models.TeamStat
 func (r *TeamStatsRepository) UpdateTeamStats(teamStats models.TeamStats) error {
 	if err := r.db.Model(&models.TeamStats{}).
 		Where("team_id = ?", teamStats.TeamID).
-		Omit("team_id", "estimation").
+		Omit("team_id", "estimation", "estimation_iterations", "estimation_std_err").
 		Save(teamStats).Error; err != nil {
 		return err
 	}
@@ -85,7 +85,7 @@ func (r *TeamStatsRepository) GetTeamStatsByLeagueID(leagueID uint) ([]models.Te
 	}
 
 	var teamStats []models.TeamStats
-	if err := r.db.Where("team_id IN ?", teamIDs).Find(&teamStats).Error; err != nil {
+	if err := r.db.Where("team_id IN ?", teamIDs).Order("team_id").Find(&teamStats).Error; err != nil {
 		return nil, err
 	}
 
@@ -99,7 +99,11 @@ func (r *TeamStatsRepository) UpdateChampionshipEstimation(estimations []dto.Cha
 			// Update each team's estimation individually
 			if err := tx.Model(&models.TeamStats{}).
 				Where("team_id = ?", est.TeamID).
-				Update("estimation", est.Estimation).Error; err != nil {
+				Updates(map[string]interface{}{
+					"estimation":            est.Estimation,
+					"estimation_iterations": est.Iterations,
+					"estimation_std_err":    est.StdErr,
+				}).Error; err != nil {
 				return err
 			}
 		}
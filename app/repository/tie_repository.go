@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"fmt"
+	"insider-case/app/database"
+	"insider-case/app/models"
+	"insider-case/app/utils"
+
+	"gorm.io/gorm"
+)
+
+type ITieRepository interface {
+	InitializeBracket(tx *gorm.DB, league models.League, rules utils.KnockoutRules, ties []models.Tie, firstWeek int) ([]models.Tie, error)
+	CreateRound(league models.League, rules utils.KnockoutRules, ties []models.Tie, firstWeek int) ([]models.Tie, error)
+	GetTiesByLeagueID(leagueID uint, stage string) ([]models.Tie, error)
+	GetTiesByLeagueIDAndRound(leagueID uint, stage string, round int) ([]models.Tie, error)
+	SaveTie(tie models.Tie) error
+}
+
+type TieRepository struct {
+	db *gorm.DB
+}
+
+var _ ITieRepository = &TieRepository{}
+
+func NewTieRepository() *TieRepository {
+	return &TieRepository{
+		db: database.GetDB(),
+	}
+}
+
+// InitializeBracket creates the ties of a round and the matches of their legs within the given transaction
+func (r *TieRepository) InitializeBracket(tx *gorm.DB, league models.League, rules utils.KnockoutRules, ties []models.Tie, firstWeek int) ([]models.Tie, error) {
+	if len(ties) == 0 {
+		return ties, nil
+	}
+	for i := range ties {
+		ties[i].LeagueID = league.ID
+	}
+	if err := tx.Create(&ties).Error; err != nil {
+		return nil, fmt.Errorf("failed to create ties: %w", err)
+	}
+
+	var matches []models.Match
+	for _, tie := range ties {
+		matches = append(matches, utils.TieMatches(league, rules, tie, firstWeek)...)
+	}
+	if len(matches) > 0 {
+		if err := tx.Create(&matches).Error; err != nil {
+			return nil, fmt.Errorf("failed to create tie matches: %w", err)
+		}
+	}
+	return ties, nil
+}
+
+// CreateRound creates the ties of a later round and their matches in a transaction of its own
+func (r *TieRepository) CreateRound(league models.League, rules utils.KnockoutRules, ties []models.Tie, firstWeek int) ([]models.Tie, error) {
+	var created []models.Tie
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = r.InitializeBracket(tx, league, rules, ties, firstWeek)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *TieRepository) GetTiesByLeagueID(leagueID uint, stage string) ([]models.Tie, error) {
+	var ties []models.Tie
+	if err := r.db.Where("league_id = ? AND stage = ?", leagueID, stage).
+		Preload("Matches", func(db *gorm.DB) *gorm.DB { return db.Order("leg") }).
+		Order("round, slot").Find(&ties).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ties for league %d: %w", leagueID, err)
+	}
+	return ties, nil
+}
+
+func (r *TieRepository) GetTiesByLeagueIDAndRound(leagueID uint, stage string, round int) ([]models.Tie, error) {
+	var ties []models.Tie
+	if err := r.db.Where("league_id = ? AND stage = ? AND round = ?", leagueID, stage, round).
+		Preload("Matches", func(db *gorm.DB) *gorm.DB { return db.Order("leg") }).
+		Order("slot").Find(&ties).Error; err != nil {
+		return nil, fmt.Errorf("failed to get round %d ties for league %d: %w", round, leagueID, err)
+	}
+	return ties, nil
+}
+
+func (r *TieRepository) SaveTie(tie models.Tie) error {
+	if err := r.db.Model(&models.Tie{}).Where("id = ?", tie.ID).
+		Select("winner_id", "decided_by", "home_aggregate", "away_aggregate", "home_penalties", "away_penalties").
+		Updates(&tie).Error; err != nil {
+		return fmt.Errorf("failed to update tie with ID %d: %w", tie.ID, err)
+	}
+	return nil
+}
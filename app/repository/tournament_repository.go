@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"fmt"
+	"insider-case/app/database"
+	"insider-case/app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ITournamentRepository interface {
+	InitializeTournament(tournament *models.Tournament, groups []*models.League) (*models.Tournament, error)
+	GetTournamentByID(id uint) (*models.Tournament, error)
+	LockTournament(id uint) (*models.Tournament, error)
+	InitializeKnockoutStage(tournament *models.Tournament, knockout *models.League, ties []models.Tie) (*models.League, error)
+}
+
+type TournamentRepository struct {
+	db               *gorm.DB
+	leagueRepository ILeagueRepository
+}
+
+var _ ITournamentRepository = &TournamentRepository{}
+
+func NewTournamentRepository(LeagueRepo ILeagueRepository) *TournamentRepository {
+	return &TournamentRepository{
+		db:               database.GetDB(),
+		leagueRepository: LeagueRepo,
+	}
+}
+
+// InitializeTournament creates the tournament and the leagues of its groups in a single transaction
+func (r *TournamentRepository) InitializeTournament(tournament *models.Tournament, groups []*models.League) (*models.Tournament, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Groups").Create(tournament).Error; err != nil {
+			return fmt.Errorf("failed to create tournament: %w", err)
+		}
+		for _, group := range groups {
+			group.TournamentID = &tournament.ID
+			if _, err := r.leagueRepository.InitializeLeagueTx(tx, group); err != nil {
+				return fmt.Errorf("failed to create %s: %w", group.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Tournament created successfully: id=%d, name=%s, groups=%d\n",
+		tournament.ID, tournament.Name, len(groups))
+
+	return r.GetTournamentByID(tournament.ID)
+}
+
+func (r *TournamentRepository) GetTournamentByID(id uint) (*models.Tournament, error) {
+	var tournament models.Tournament
+	if err := r.db.Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&tournament, id).Error; err != nil {
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// LockTournament reads the tournament with its groups and locks its row until the end of the
+// transaction, so the rounds of the tournament are played one request at a time
+func (r *TournamentRepository) LockTournament(id uint) (*models.Tournament, error) {
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Tournament{}, id).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock tournament %d: %w", id, err)
+	}
+	return r.GetTournamentByID(id)
+}
+
+// InitializeKnockoutStage creates the knockout stage of the tournament and records it on the tournament
+func (r *TournamentRepository) InitializeKnockoutStage(tournament *models.Tournament, knockout *models.League, ties []models.Tie) (*models.League, error) {
+	var created *models.League
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if created, err = r.leagueRepository.InitializeKnockout(tx, knockout, ties); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Tournament{}).Where("id = ? AND knockout_id IS NULL", tournament.ID).
+			Update("knockout_id", created.ID).Error; err != nil {
+			return fmt.Errorf("failed to record knockout stage of tournament %d: %w", tournament.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	tournament.KnockoutID = &created.ID
+	return created, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"insider-case/app/database"
+
+	"gorm.io/gorm"
+)
+
+// Repositories are the repositories of a unit of work, every one of them reading and writing
+// through the transaction of the unit
+type Repositories struct {
+	Leagues     ILeagueRepository
+	Matches     IMatchRepository
+	Teams       ITeamRepository
+	TeamStats   ITeamStatsRepository
+	TeamRatings ITeamRatingRepository
+	WeeklyLogs  IWeeklyLogRepository
+	Projections IProjectionRepository
+	Ties        ITieRepository
+	Clubs       IClubRepository
+	Audits      IAuditRepository
+	Tournaments ITournamentRepository
+	Pyramids    IPyramidRepository
+
+	// UnitOfWork nests further units of work in the transaction as savepoints
+	UnitOfWork IUnitOfWork
+}
+
+type IUnitOfWork interface {
+	// Do runs fn in a single transaction, committed when fn returns nil and rolled back otherwise
+	Do(fn func(repos Repositories) error) error
+}
+
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+var _ IUnitOfWork = &UnitOfWork{}
+
+func NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{
+		db: database.GetDB(),
+	}
+}
+
+func (u *UnitOfWork) Do(fn func(repos Repositories) error) error {
+	return u.db.Transaction(func(tx *gorm.DB) error {
+		return fn(newRepositories(tx))
+	})
+}
+
+// newRepositories binds every repository to the given transaction
+func newRepositories(tx *gorm.DB) Repositories {
+	teams := &TeamRepository{db: tx}
+	matches := &MatchRepository{db: tx}
+	teamStats := &TeamStatsRepository{db: tx}
+	teamRatings := &TeamRatingRepository{db: tx}
+	ties := &TieRepository{db: tx}
+	leagues := &LeagueRepository{
+		db:                   tx,
+		teamRepository:       teams,
+		matchRepository:      matches,
+		teamStatsRepository:  teamStats,
+		teamRatingRepository: teamRatings,
+		tieRepository:        ties,
+	}
+	return Repositories{
+		Leagues:     leagues,
+		Matches:     matches,
+		Teams:       teams,
+		TeamStats:   teamStats,
+		TeamRatings: teamRatings,
+		WeeklyLogs:  &WeeklyLogRepository{db: tx, teamStatsRepo: teamStats},
+		Projections: &ProjectionRepository{db: tx},
+		Ties:        ties,
+		Clubs:       &ClubRepository{db: tx},
+		Audits:      &AuditRepository{db: tx},
+		Tournaments: &TournamentRepository{db: tx, leagueRepository: leagues},
+		Pyramids:    &PyramidRepository{db: tx, leagueRepository: leagues},
+		UnitOfWork:  &UnitOfWork{db: tx},
+	}
+}
@@ -11,6 +11,8 @@ import (
 
 type IWeeklyLogRepository interface {
 	SaveWeeklyLog(leagueID uint, week int) error
+	ReplaceWeeklyLog(leagueID uint, week int) error
+	UpdateWeeklyLog(leagueID uint, week int, teamStats []models.TeamStats) error
 	GetWeeklyLogByLeagueIDAndWeek(leagueID uint, week int) (*models.WeeklyLog, error)
 }
 
@@ -28,21 +30,14 @@ func NewWeeklyLogRepository(teamStatsRepo ITeamStatsRepository) *WeeklyLogReposi
 	}
 }
 func (r *WeeklyLogRepository) SaveWeeklyLog(leagueID uint, week int) error {
-
-	log := models.WeeklyLog{
-		LeagueID:      leagueID,
-		Week:          week,
-		TeamStatsJSON: "{}", // Placeholder for team stats JSON, should be populated with actual data
-	}
 	teamStats, err := r.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
 	if err != nil {
 		return fmt.Errorf("failed to get team stats for league %d: %w", leagueID, err)
 	}
-	teamStatsJSON, err := json.Marshal(&teamStats)
+	log, err := newWeeklyLog(leagueID, week, teamStats)
 	if err != nil {
-		return fmt.Errorf("failed to marshal team stats to JSON: %w", err)
+		return err
 	}
-	log.TeamStatsJSON = string(teamStatsJSON)
 
 	if err := r.db.Create(&log).Error; err != nil {
 		return fmt.Errorf("failed to save weekly log: %w", err)
@@ -50,6 +45,40 @@ func (r *WeeklyLogRepository) SaveWeeklyLog(leagueID uint, week int) error {
 	fmt.Println("Weekly log saved successfully for league:", log.LeagueID, "week:", log.Week)
 	return nil
 }
+
+// ReplaceWeeklyLog replaces the weekly log of the week with the current team stats
+func (r *WeeklyLogRepository) ReplaceWeeklyLog(leagueID uint, week int) error {
+	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).Delete(&models.WeeklyLog{}).Error; err != nil {
+		return fmt.Errorf("failed to delete weekly log: %w", err)
+	}
+	return r.SaveWeeklyLog(leagueID, week)
+}
+
+// UpdateWeeklyLog replaces the snapshot of the team stats held by the weekly log of the week
+func (r *WeeklyLogRepository) UpdateWeeklyLog(leagueID uint, week int, teamStats []models.TeamStats) error {
+	log, err := newWeeklyLog(leagueID, week, teamStats)
+	if err != nil {
+		return err
+	}
+	if err := r.db.Model(&models.WeeklyLog{}).Where("league_id = ? AND week = ?", leagueID, week).
+		Update("team_stats_json", log.TeamStatsJSON).Error; err != nil {
+		return fmt.Errorf("failed to update weekly log of week %d: %w", week, err)
+	}
+	return nil
+}
+
+// newWeeklyLog returns the weekly log holding the snapshot of the team stats at the end of the week
+func newWeeklyLog(leagueID uint, week int, teamStats []models.TeamStats) (models.WeeklyLog, error) {
+	teamStatsJSON, err := json.Marshal(&teamStats)
+	if err != nil {
+		return models.WeeklyLog{}, fmt.Errorf("failed to marshal team stats to JSON: %w", err)
+	}
+	return models.WeeklyLog{
+		LeagueID:      leagueID,
+		Week:          week,
+		TeamStatsJSON: string(teamStatsJSON),
+	}, nil
+}
 func (r *WeeklyLogRepository) GetWeeklyLogByLeagueIDAndWeek(leagueID uint, week int) (*models.WeeklyLog, error) {
 	var log models.WeeklyLog
 	if err := r.db.Where("league_id = ? AND week = ?", leagueID, week).First(&log).Error; err != nil {
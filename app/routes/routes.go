@@ -14,39 +14,124 @@ func RegisterRoutes(r *mux.Router) {
 	teamRepo := repository.NewTeamRepository()
 	matchRepo := repository.NewMatchRepository()
 	teamStatsRepo := repository.NewTeamStatsRepository()
+	teamRatingRepo := repository.NewTeamRatingRepository()
 	weeklyLogRepo := repository.NewWeeklyLogRepository(teamStatsRepo)
-	leagueRepo := repository.NewLeagueRepository(teamRepo, matchRepo, teamStatsRepo)
-	matchService := services.NewMatchService(matchRepo, teamRepo, teamStatsRepo)
+	projectionRepo := repository.NewProjectionRepository()
+	tieRepo := repository.NewTieRepository()
+	clubRepo := repository.NewClubRepository()
+	auditRepo := repository.NewAuditRepository()
+	unitOfWork := repository.NewUnitOfWork()
+	leagueRepo := repository.NewLeagueRepository(teamRepo, matchRepo, teamStatsRepo, teamRatingRepo, tieRepo)
+	matchService := services.NewMatchService(matchRepo, teamRepo, teamStatsRepo, teamRatingRepo)
+
+	tournamentRepo := repository.NewTournamentRepository(leagueRepo)
+	pyramidRepo := repository.NewPyramidRepository(leagueRepo)
+	cupService := services.NewCupService(
+		leagueRepo,
+		tieRepo,
+		matchService,
+		teamRepo,
+		teamStatsRepo,
+		unitOfWork,
+	)
+	leagueService := services.NewLeagueService(
+		leagueRepo,
+		matchService,
+		teamStatsRepo,
+		weeklyLogRepo,
+		teamRepo,
+		projectionRepo,
+		tieRepo,
+		cupService,
+		clubRepo,
+		auditRepo,
+		teamRatingRepo,
+		unitOfWork,
+	)
 
 	leagueController := controllers.NewLeagueController(
-		services.NewLeagueService(
-			leagueRepo,
-			matchService,
-			teamStatsRepo,
-			weeklyLogRepo,
-			teamRepo,
-		),
+		leagueService,
 	)
 	teamController := controllers.NewTeamController(
 		services.NewTeamService(
 			teamRepo,
 			teamStatsRepo,
+			teamRatingRepo,
 		),
 	)
 	matchController := controllers.NewMatchController(
 		matchService,
 	)
+	cupController := controllers.NewCupController(
+		cupService,
+	)
+	tournamentController := controllers.NewTournamentController(
+		services.NewTournamentService(
+			tournamentRepo,
+			leagueRepo,
+			tieRepo,
+			leagueService,
+			cupService,
+			unitOfWork,
+		),
+	)
+	pyramidController := controllers.NewPyramidController(
+		services.NewPyramidService(
+			pyramidRepo,
+			leagueRepo,
+			leagueService,
+			unitOfWork,
+		),
+	)
+	adminController := controllers.NewAdminController(
+		services.NewStatsService(
+			leagueRepo,
+			matchRepo,
+			teamStatsRepo,
+			unitOfWork,
+		),
+	)
 
 	api := mux.NewRouter().PathPrefix("/api").Subrouter()
 	api.HandleFunc("/leagues", leagueController.CreateLeague).Methods("POST")
 
 	api.HandleFunc("/teams/{leagueID}", teamController.GetTeamsByLeagueID).Methods("GET")
+	api.HandleFunc("/teams/{teamID}/ratings", teamController.GetTeamRatings).Methods("GET")
 	api.HandleFunc("/matches/{leagueID}/{week}", matchController.GetMatchesByLeagueIDAndWeek).Methods("GET")
 	api.HandleFunc("/matches/{leagueID}", matchController.GetMatchesByLeagueID).Methods("GET")
+	api.HandleFunc("/matches/{id}", leagueController.EditMatchResult).Methods("PUT")
 	api.HandleFunc("/leagues/simulate-week", leagueController.SimulateWeek).Methods("POST")
 	api.HandleFunc("/leagues/play-remaining-matches", leagueController.PlayRemainingMatches).Methods("POST")
 	api.HandleFunc("/leagues/user-play-week", leagueController.UserPlayWeek).Methods("POST")
 	api.HandleFunc("/leagues/championship-estimations", leagueController.GetChampionshipEstimations).Methods("GET")
+	api.HandleFunc("/leagues/{id}/projections", leagueController.GetProjections).Methods("GET")
+	api.HandleFunc("/leagues/{id}/standings", leagueController.GetStandings).Methods("GET")
+	api.HandleFunc("/leagues/{id}/next-season", leagueController.NextSeason).Methods("POST")
+	api.HandleFunc("/leagues/{id}/all-time-table", leagueController.GetAllTimeTable).Methods("GET")
+	api.HandleFunc("/leagues/{id}/rewind", leagueController.RewindLeague).Methods("POST")
+	api.HandleFunc("/leagues/{id}/undo-week", leagueController.UndoWeek).Methods("POST")
+	api.HandleFunc("/leagues/{id}/audit", leagueController.GetAuditTrail).Methods("GET")
+
+	api.HandleFunc("/cups", cupController.CreateCup).Methods("POST")
+	api.HandleFunc("/cups/{id}/simulate-round", cupController.SimulateRound).Methods("POST")
+	api.HandleFunc("/cups/{id}/bracket", cupController.GetBracket).Methods("GET")
+	api.HandleFunc("/cups/{id}/probabilities", cupController.GetRoundProbabilities).Methods("GET")
+
+	api.HandleFunc("/tournaments", tournamentController.CreateTournament).Methods("POST")
+	api.HandleFunc("/tournaments/{id}", tournamentController.GetTournament).Methods("GET")
+	api.HandleFunc("/tournaments/{id}/simulate-round", tournamentController.SimulateRound).Methods("POST")
+	api.HandleFunc("/tournaments/{id}/simulate", tournamentController.SimulateAll).Methods("POST")
+	api.HandleFunc("/tournaments/{id}/probabilities", tournamentController.GetProbabilities).Methods("GET")
+
+	api.HandleFunc("/pyramids", pyramidController.CreatePyramid).Methods("POST")
+	api.HandleFunc("/pyramids/{id}", pyramidController.GetPyramid).Methods("GET")
+	api.HandleFunc("/pyramids/{id}/simulate-week", pyramidController.SimulateWeek).Methods("POST")
+	api.HandleFunc("/pyramids/{id}/simulate", pyramidController.SimulateSeason).Methods("POST")
+	api.HandleFunc("/pyramids/{id}/probabilities", pyramidController.GetProbabilities).Methods("GET")
+	api.HandleFunc("/pyramids/{id}/next-season", pyramidController.NextSeason).Methods("POST")
+
+	api.HandleFunc("/admin/leagues/{id}/rebuild-stats", adminController.RebuildStats).Methods("POST")
+	api.HandleFunc("/admin/stats-check", adminController.CheckStats).Methods("GET")
 
 	r.PathPrefix("/api").Handler(enableCORS(api))
 
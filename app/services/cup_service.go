@@ -0,0 +1,387 @@
+package services
+
+import (
+	"fmt"
+	"insider-case/app/dto"
+	"insider-case/app/helpers"
+	"insider-case/app/models"
+	"insider-case/app/repository"
+	"insider-case/app/utils"
+)
+
+type ICupService interface {
+	CreateCup(req dto.CupCreateRequest) (*dto.Bracket, error)
+	GetBracket(cupID uint) (*dto.Bracket, error)
+	SimulateRound(cupID uint) (*dto.CupRound, error)
+	GetRoundProbabilities(cupID uint, req dto.EstimationRequest) (*dto.KnockoutProjection, error)
+	PlayRound(league models.League, stage string, round, rounds int, rules utils.KnockoutRules, nextWeek int) ([]models.Tie, error)
+	WithRepositories(repos repository.Repositories) ICupService
+}
+
+type CupService struct {
+	repo          repository.ILeagueRepository
+	tieRepo       repository.ITieRepository
+	matchService  IMatchService
+	teamRepo      repository.ITeamRepository
+	teamStatsRepo repository.ITeamStatsRepository
+	uow           repository.IUnitOfWork
+}
+
+var _ ICupService = &CupService{}
+
+func NewCupService(repo repository.ILeagueRepository, tieRepo repository.ITieRepository, matchService IMatchService, teamRepo repository.ITeamRepository, teamStatsRepo repository.ITeamStatsRepository, uow repository.IUnitOfWork) *CupService {
+	return &CupService{
+		repo:          repo,
+		tieRepo:       tieRepo,
+		matchService:  matchService,
+		teamRepo:      teamRepo,
+		teamStatsRepo: teamStatsRepo,
+		uow:           uow,
+	}
+}
+
+// WithRepositories returns a copy of the service working through the repositories of a unit of work
+func (s *CupService) WithRepositories(repos repository.Repositories) ICupService {
+	return s.withRepositories(repos)
+}
+
+func (s *CupService) withRepositories(repos repository.Repositories) *CupService {
+	return &CupService{
+		repo:          repos.Leagues,
+		tieRepo:       repos.Ties,
+		matchService:  s.matchService.WithRepositories(repos),
+		teamRepo:      repos.Teams,
+		teamStatsRepo: repos.TeamStats,
+		uow:           repos.UnitOfWork,
+	}
+}
+
+func (s *CupService) CreateCup(req dto.CupCreateRequest) (*dto.Bracket, error) {
+	if err := helpers.ValidateTeamCount(len(req.Teams)); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateTeamStrength(req.Teams); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateEngine(req.Engine); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateEngineParams(req.EngineParams); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateSimulationSettings(req.SimulationIterations, req.TargetStdErr); err != nil {
+		return nil, err
+	}
+	if req.SimulationIterations == 0 {
+		req.SimulationIterations = utils.DefaultSimulationIterations
+	}
+	if err := helpers.ValidateKnockoutLegs(req.Legs); err != nil {
+		return nil, err
+	}
+	legs := utils.MinKnockoutLegs
+	if req.Legs != nil {
+		legs = *req.Legs
+	}
+	if req.Engine == "" {
+		req.Engine = utils.DefaultEngine
+	}
+	seed := utils.GenerateSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	cup := &models.League{
+		Name:         req.Name,
+		Format:       utils.FormatKnockout,
+		TeamCount:    len(req.Teams),
+		Legs:         legs,
+		AwayGoals:    req.AwayGoals,
+		Seeded:       req.Seeded,
+		Engine:       req.Engine,
+		EngineParams: engineParamsFromRequest(req.EngineParams),
+		UseRating:    req.UseRating,
+		Seed:         seed,
+
+		SimulationIterations: req.SimulationIterations,
+		TargetStdErr:         req.TargetStdErr,
+
+		Teams: make([]models.Team, len(req.Teams)),
+	}
+	for i, team := range req.Teams {
+		cup.Teams[i] = models.Team{
+			Name:     team.Name,
+			Strength: team.Strength,
+		}
+	}
+
+	createdCup, err := s.repo.InitializeCup(cup)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize cup: %w", err)
+	}
+	return s.GetBracket(createdCup.ID)
+}
+
+// GetBracket returns every tie drawn so far and the champion once the final is played
+func (s *CupService) GetBracket(cupID uint) (*dto.Bracket, error) {
+	cup, err := s.getCup(cupID)
+	if err != nil {
+		return nil, err
+	}
+	ties, err := s.tieRepo.GetTiesByLeagueID(cupID, utils.StageCup)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.bracketTeams(ties)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := utils.NewKnockoutRules(*cup)
+	rounds := utils.KnockoutRounds(cup.TeamCount)
+	bracket := &dto.Bracket{
+		LeagueID:     cup.ID,
+		Name:         cup.Name,
+		Rounds:       rounds,
+		CurrentRound: utils.KnockoutRound(cup.CurrWeek, rules.Legs),
+		Legs:         rules.Legs,
+		AwayGoals:    rules.AwayGoals,
+		Seeded:       cup.Seeded,
+		Seed:         cup.Seed,
+		Teams:        teams,
+		Ties:         ties,
+	}
+	if bracket.Champion, err = s.champion(ties, rounds); err != nil {
+		return nil, err
+	}
+	return bracket, nil
+}
+
+// SimulateRound plays every undecided tie of the current round and draws the next round from the
+// winners. The round is committed as a whole: a failure leaves the cup as it was before the round.
+func (s *CupService) SimulateRound(cupID uint) (*dto.CupRound, error) {
+	var round *dto.CupRound
+	err := s.uow.Do(func(repos repository.Repositories) error {
+		var err error
+		round, err = s.withRepositories(repos).simulateRound(cupID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return round, nil
+}
+
+func (s *CupService) simulateRound(cupID uint) (*dto.CupRound, error) {
+	cup, err := s.getCup(cupID)
+	if err != nil {
+		return nil, err
+	}
+	rules := utils.NewKnockoutRules(*cup)
+	rounds := utils.KnockoutRounds(cup.TeamCount)
+	round := utils.KnockoutRound(cup.CurrWeek, rules.Legs)
+	if round > rounds {
+		return nil, fmt.Errorf("cup %d is already decided", cupID)
+	}
+
+	// Claim the round before playing it, every leg takes a week of the cup calendar
+	version := cup.Version
+	for leg := 0; leg < rules.Legs; leg++ {
+		updatedCup, err := s.repo.IncrementWeek(cupID, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to increment cup week: %w", err)
+		}
+		version = updatedCup.Version
+	}
+
+	ties, err := s.PlayRound(*cup, utils.StageCup, round, rounds, rules, round*rules.Legs+1)
+	if err != nil {
+		return nil, err
+	}
+
+	champion, err := s.champion(ties, rounds)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CupRound{
+		LeagueID: cupID,
+		Round:    round,
+		Ties:     ties,
+		Champion: champion,
+	}, nil
+}
+
+// PlayRound plays every undecided tie of a round of the given stage of the competition and, unless
+// it is the final, draws the next round from the winners with its first leg in nextWeek
+func (s *CupService) PlayRound(league models.League, stage string, round, rounds int, rules utils.KnockoutRules, nextWeek int) ([]models.Tie, error) {
+	ties, err := s.tieRepo.GetTiesByLeagueIDAndRound(league.ID, stage, round)
+	if err != nil {
+		return nil, err
+	}
+	if len(ties) == 0 {
+		return nil, fmt.Errorf("no %s ties found for competition %d and round %d", stage, league.ID, round)
+	}
+
+	engine, err := utils.NewMatchEngine(league)
+	if err != nil {
+		return nil, err
+	}
+	teamStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(league.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team stats for competition %d: %w", league.ID, err)
+	}
+
+	for i, tie := range ties {
+		if tie.WinnerID != nil {
+			continue
+		}
+		playedTie, err := s.playTie(tie, engine, rules, teamStats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to play tie %d: %w", tie.ID, err)
+		}
+		ties[i] = playedTie
+	}
+
+	if round < rounds {
+		next, err := utils.NextRoundTies(ties)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.tieRepo.CreateRound(league, rules, next, nextWeek); err != nil {
+			return nil, fmt.Errorf("failed to draw round %d of competition %d: %w", round+1, league.ID, err)
+		}
+	}
+	return ties, nil
+}
+
+// playTie plays the legs of a tie from the seed of its first leg and records them
+func (s *CupService) playTie(tie models.Tie, engine utils.MatchEngine, rules utils.KnockoutRules, teamStats []models.TeamStats) (models.Tie, error) {
+	if len(tie.Matches) != rules.Legs {
+		return tie, fmt.Errorf("tie has %d matches but %d legs are played", len(tie.Matches), rules.Legs)
+	}
+	homeTeam, err := s.teamRepo.GetTeamByID(*tie.HomeTeamID)
+	if err != nil {
+		return tie, fmt.Errorf("failed to get home team %d: %w", *tie.HomeTeamID, err)
+	}
+	awayTeam, err := s.teamRepo.GetTeamByID(*tie.AwayTeamID)
+	if err != nil {
+		return tie, fmt.Errorf("failed to get away team %d: %w", *tie.AwayTeamID, err)
+	}
+
+	outcome := utils.PlayTie(engine, rules, homeTeam, awayTeam, teamStats, utils.NewRand(tie.Matches[0].Seed))
+	for i, result := range outcome.Legs {
+		playedMatch, err := s.matchService.RecordTieLeg(tie.Matches[i], result)
+		if err != nil {
+			return tie, err
+		}
+		tie.Matches[i] = playedMatch
+	}
+
+	tie.HomeAggregate = outcome.HomeAggregate
+	tie.AwayAggregate = outcome.AwayAggregate
+	tie.HomePenalties = outcome.HomePenalties
+	tie.AwayPenalties = outcome.AwayPenalties
+	tie.DecidedBy = outcome.DecidedBy
+	tie.WinnerID = tie.AwayTeamID
+	if outcome.HomeWins {
+		tie.WinnerID = tie.HomeTeamID
+	}
+	if err := s.tieRepo.SaveTie(tie); err != nil {
+		return tie, err
+	}
+	return tie, nil
+}
+
+// GetRoundProbabilities estimates the probability of every team reaching each of the remaining rounds
+func (s *CupService) GetRoundProbabilities(cupID uint, req dto.EstimationRequest) (*dto.KnockoutProjection, error) {
+	if err := helpers.ValidateSimulationSettings(req.Iterations, req.StdErr); err != nil {
+		return nil, err
+	}
+	cup, err := s.getCup(cupID)
+	if err != nil {
+		return nil, err
+	}
+	ties, err := s.tieRepo.GetTiesByLeagueID(cupID, utils.StageCup)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.bracketTeams(ties)
+	if err != nil {
+		return nil, err
+	}
+	teamStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(cupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team stats for cup %d: %w", cupID, err)
+	}
+	engine, err := utils.NewMatchEngine(*cup)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := utils.NewKnockoutRules(*cup)
+	state := dto.KnockoutState{
+		LeagueID:  cup.ID,
+		Round:     utils.KnockoutRound(cup.CurrWeek, rules.Legs),
+		Rounds:    utils.KnockoutRounds(cup.TeamCount),
+		Teams:     teams,
+		TeamStats: teamStats,
+		Ties:      ties,
+	}
+
+	opts := utils.NewSimulationOptions(*cup, cup.CurrWeek-1)
+	if req.Iterations != 0 {
+		opts.Iterations = req.Iterations
+	}
+	if req.StdErr != 0 {
+		opts.TargetStdErr = req.StdErr
+	}
+
+	projection, err := utils.EstimateKnockoutProbabilities(state, engine, rules, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate round probabilities: %w", err)
+	}
+	return projection, nil
+}
+
+// getCup returns the competition with the given ID, failing when it is not a knockout competition
+func (s *CupService) getCup(cupID uint) (*models.League, error) {
+	cup, err := s.repo.GetLeagueByID(cupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cup with ID %d: %w", cupID, err)
+	}
+	if cup.Format != utils.FormatKnockout {
+		return nil, fmt.Errorf("competition %d is not a knockout cup", cupID)
+	}
+	return cup, nil
+}
+
+// bracketTeams returns the teams drawn in the first round of the bracket. The knockout stage of a
+// tournament is played by the teams of its groups, so they are not looked up by league.
+func (s *CupService) bracketTeams(ties []models.Tie) ([]models.Team, error) {
+	var teamIDs []uint
+	for _, tie := range ties {
+		if tie.Round != 1 {
+			continue
+		}
+		for _, teamID := range []*uint{tie.HomeTeamID, tie.AwayTeamID} {
+			if teamID != nil {
+				teamIDs = append(teamIDs, *teamID)
+			}
+		}
+	}
+	return s.teamRepo.GetTeamsByIDs(teamIDs)
+}
+
+// champion returns the winner of the final, nil while it is not played
+func (s *CupService) champion(ties []models.Tie, rounds int) (*models.Team, error) {
+	for _, tie := range ties {
+		if tie.Round != rounds || tie.WinnerID == nil {
+			continue
+		}
+		champion, err := s.teamRepo.GetTeamByID(*tie.WinnerID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get champion team by ID %d: %w", *tie.WinnerID, err)
+		}
+		return &champion, nil
+	}
+	return nil, nil
+}
@@ -1,39 +1,98 @@
 package services
 
 import (
+	"encoding/json"
 	"fmt"
 	"insider-case/app/dto"
 	"insider-case/app/helpers"
 	"insider-case/app/models"
 	"insider-case/app/repository"
 	"insider-case/app/utils"
+	"sort"
 )
 
 type ILeagueService interface {
 	InitializeLeague(req dto.LeagueCreateRequest) (*dto.LeagueResponse, error)
-	SimulateWeek(leagueID uint) (*dto.Week, error)
+	SimulateWeek(leagueID uint, expectedWeek *int) (*dto.Week, error)
+	PlayWeek(leagueID uint) (*dto.Week, error)
+	PublishEstimations(leagueID uint) error
+	WithRepositories(repos repository.Repositories) ILeagueService
 	PlayRemainingMatches(leagueID uint) ([]*dto.Week, error)
 	UserPlayWeek(matches []dto.UserPlayedMatch) (*dto.Week, error)
-	GetChampionshipEstimationByLeagueID(leagueID uint) ([]dto.ChampionshipEstimation, error)
+	GetChampionshipEstimationByLeagueID(leagueID uint, req dto.EstimationRequest) ([]dto.ChampionshipEstimation, error)
+	GetProjections(leagueID uint, week *int) (*dto.LeagueProjection, error)
+	GetStandings(leagueID uint) (*dto.Standings, error)
+	GetLeagueState(leagueID uint) (*dto.LeagueState, error)
+	EstimateProjection(leagueID uint, req dto.EstimationRequest) (*dto.LeagueProjection, error)
+	NextSeason(leagueID uint, req dto.NextSeasonRequest) (*dto.LeagueResponse, error)
+	GetAllTimeTable(leagueID uint) (*dto.AllTimeTable, error)
+	RewindLeague(leagueID uint, req dto.RewindRequest) (*dto.Standings, error)
+	UndoWeek(leagueID uint) (*dto.Standings, error)
+	EditMatchResult(matchID uint, req dto.MatchResultRequest) (*models.Match, error)
+	GetAuditTrail(leagueID uint) ([]models.LeagueAudit, error)
 }
 
 type LeagueService struct {
-	repo          repository.ILeagueRepository
-	matchService  IMatchService
-	teamStatsRepo repository.ITeamStatsRepository
-	weeklyLogRepo repository.IWeeklyLogRepository
-	teamRepo      repository.ITeamRepository
+	repo           repository.ILeagueRepository
+	matchService   IMatchService
+	teamStatsRepo  repository.ITeamStatsRepository
+	weeklyLogRepo  repository.IWeeklyLogRepository
+	teamRepo       repository.ITeamRepository
+	projectionRepo repository.IProjectionRepository
+	tieRepo        repository.ITieRepository
+	cupService     ICupService
+	clubRepo       repository.IClubRepository
+	auditRepo      repository.IAuditRepository
+	teamRatingRepo repository.ITeamRatingRepository
+	uow            repository.IUnitOfWork
 }
 
 var _ ILeagueService = &LeagueService{}
 
-func NewLeagueService(repo repository.ILeagueRepository, matchService IMatchService, teamStatsRepo repository.ITeamStatsRepository, weeklyLogRepo repository.IWeeklyLogRepository, teamRepo repository.ITeamRepository) *LeagueService {
+func NewLeagueService(repo repository.ILeagueRepository, matchService IMatchService, teamStatsRepo repository.ITeamStatsRepository, weeklyLogRepo repository.IWeeklyLogRepository, teamRepo repository.ITeamRepository, projectionRepo repository.IProjectionRepository, tieRepo repository.ITieRepository, cupService ICupService, clubRepo repository.IClubRepository, auditRepo repository.IAuditRepository, teamRatingRepo repository.ITeamRatingRepository, uow repository.IUnitOfWork) *LeagueService {
 	return &LeagueService{
-		repo:          repo,
-		matchService:  matchService,
-		teamStatsRepo: teamStatsRepo,
-		weeklyLogRepo: weeklyLogRepo,
-		teamRepo:      teamRepo,
+		repo:           repo,
+		matchService:   matchService,
+		teamStatsRepo:  teamStatsRepo,
+		weeklyLogRepo:  weeklyLogRepo,
+		teamRepo:       teamRepo,
+		projectionRepo: projectionRepo,
+		tieRepo:        tieRepo,
+		cupService:     cupService,
+		clubRepo:       clubRepo,
+		auditRepo:      auditRepo,
+		teamRatingRepo: teamRatingRepo,
+		uow:            uow,
+	}
+}
+
+// inTransaction runs fn with a copy of the service whose repositories work through a single
+// transaction, so everything fn writes is committed together or not at all
+func (s *LeagueService) inTransaction(fn func(tx *LeagueService) error) error {
+	return s.uow.Do(func(repos repository.Repositories) error {
+		return fn(s.withRepositories(repos))
+	})
+}
+
+// WithRepositories returns a copy of the service working through the repositories of a unit of work
+func (s *LeagueService) WithRepositories(repos repository.Repositories) ILeagueService {
+	return s.withRepositories(repos)
+}
+
+func (s *LeagueService) withRepositories(repos repository.Repositories) *LeagueService {
+	return &LeagueService{
+		repo:           repos.Leagues,
+		matchService:   s.matchService.WithRepositories(repos),
+		teamStatsRepo:  repos.TeamStats,
+		weeklyLogRepo:  repos.WeeklyLogs,
+		teamRepo:       repos.Teams,
+		projectionRepo: repos.Projections,
+		tieRepo:        repos.Ties,
+		cupService:     s.cupService.WithRepositories(repos),
+		clubRepo:       repos.Clubs,
+		auditRepo:      repos.Audits,
+		teamRatingRepo: repos.TeamRatings,
+		uow:            repos.UnitOfWork,
 	}
 }
 
@@ -44,6 +103,64 @@ func (s *LeagueService) InitializeLeague(req dto.LeagueCreateRequest) (*dto.Leag
 	if err := helpers.ValidateTeamStrength(req.Teams); err != nil {
 		return nil, err
 	}
+	if err := helpers.ValidateEngine(req.Engine); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateEngineParams(req.EngineParams); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateSimulationSettings(req.SimulationIterations, req.TargetStdErr); err != nil {
+		return nil, err
+	}
+	if req.SimulationIterations == 0 {
+		req.SimulationIterations = utils.DefaultSimulationIterations
+	}
+	if err := helpers.ValidateLegs(req.Legs); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateLeagueFormat(req.Format, req.Legs, req.Rounds, req.TeamCount); err != nil {
+		return nil, err
+	}
+	format := utils.FormatLeague
+	legs := utils.DefaultLegs
+	if req.Legs != nil {
+		legs = *req.Legs
+	}
+	maxWeeks := helpers.CalculateMaxWeeks(req.TeamCount, legs)
+	if req.Format == utils.FormatSwiss {
+		// Teams meet at most once and every round is paired once the previous one is played
+		format, legs, maxWeeks = utils.FormatSwiss, 1, utils.DefaultSwissRounds(req.TeamCount)
+		if req.Rounds != nil {
+			maxWeeks = *req.Rounds
+		}
+	}
+	if err := helpers.ValidateEstimationStartWeek(req.EstimationStartWeek, maxWeeks); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateTieBreakers(req.TieBreakers); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidatePlayoffs(req.Playoffs, req.TeamCount); err != nil {
+		return nil, err
+	}
+	if len(req.TieBreakers) == 0 {
+		req.TieBreakers = utils.DefaultTieBreakers
+	}
+	points := pointsSystemFromRequest(req.Points)
+	if err := helpers.ValidatePointsSystem(points); err != nil {
+		return nil, err
+	}
+	estimationStartWeek := 0
+	if req.EstimationStartWeek != nil {
+		estimationStartWeek = *req.EstimationStartWeek
+	}
+	if req.Engine == "" {
+		req.Engine = utils.DefaultEngine
+	}
+	seed := utils.GenerateSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
 
 	if len(req.Teams) != req.TeamCount {
 		return nil, &helpers.ValidationError{
@@ -54,10 +171,26 @@ func (s *LeagueService) InitializeLeague(req dto.LeagueCreateRequest) (*dto.Leag
 
 	// Convert DTO to model
 	league := &models.League{
-		Name:      req.Name,
-		TeamCount: req.TeamCount,
-		MaxWeeks:  helpers.CalculateMaxWeeks(req.TeamCount),
-		Teams:     make([]models.Team, len(req.Teams)),
+		Name:         req.Name,
+		Format:       format,
+		TeamCount:    req.TeamCount,
+		MaxWeeks:     maxWeeks,
+		Legs:         legs,
+		Engine:       req.Engine,
+		EngineParams: engineParamsFromRequest(req.EngineParams),
+		UseRating:    req.UseRating,
+		Seed:         seed,
+		Season:       utils.FirstSeason,
+
+		SimulationIterations: req.SimulationIterations,
+		TargetStdErr:         req.TargetStdErr,
+		EstimationStartWeek:  estimationStartWeek,
+
+		TieBreakers: req.TieBreakers,
+		Points:      points,
+		Playoffs:    playoffSystemFromRequest(req.Playoffs),
+
+		Teams: make([]models.Team, len(req.Teams)),
 	}
 
 	for i, team := range req.Teams {
@@ -67,33 +200,140 @@ func (s *LeagueService) InitializeLeague(req dto.LeagueCreateRequest) (*dto.Leag
 		}
 	}
 
+	return s.createLeague(league)
+}
+
+// createLeague creates the league with its teams and fixtures and publishes its pre-season projection
+func (s *LeagueService) createLeague(league *models.League) (*dto.LeagueResponse, error) {
 	// Call repository
 	createdLeague, err := s.repo.InitializeLeague(league)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize league: %w", err)
 	}
 
+	// Pre-season projection
+	if estimationsAvailable(createdLeague, 0) {
+		if err := s.updateChampionshipProbabilities(createdLeague, 0); err != nil {
+			return nil, fmt.Errorf("failed to update championship probabilities: %w", err)
+		}
+		for i, team := range createdLeague.Teams {
+			stats, err := s.teamStatsRepo.GetTeamStatsByTeamID(team.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get team stats for team %d: %w", team.ID, err)
+			}
+			createdLeague.Teams[i].Stats = stats
+		}
+	}
+
 	// Convert model to response DTO
 	return convertToLeagueResponse(createdLeague), nil
 }
 
+// engineParamsFromRequest fills the parameters missing from the request with the defaults
+func engineParamsFromRequest(req *dto.EngineParamsRequest) models.EngineParams {
+	params := utils.DefaultEngineParams()
+	if req == nil {
+		return params
+	}
+	if req.BaseGoals != nil {
+		params.BaseGoals = *req.BaseGoals
+	}
+	if req.HomeAdvantage != nil {
+		params.HomeAdvantage = *req.HomeAdvantage
+	}
+	if req.StrengthWeight != nil {
+		params.StrengthWeight = *req.StrengthWeight
+	}
+	if req.FormWeight != nil {
+		params.FormWeight = *req.FormWeight
+	}
+	return params
+}
+
+// pointsSystemFromRequest fills the points missing from the request with the defaults
+func pointsSystemFromRequest(req *dto.PointsSystemRequest) models.PointsSystem {
+	points := utils.DefaultPointsSystem()
+	if req == nil {
+		return points
+	}
+	for _, field := range []struct {
+		value  *int
+		target *int
+	}{
+		{req.Win, &points.Win},
+		{req.Draw, &points.Draw},
+		{req.Loss, &points.Loss},
+		{req.GoalsBonusThreshold, &points.GoalsBonusThreshold},
+		{req.GoalsBonus, &points.GoalsBonus},
+		{req.LosingBonusMargin, &points.LosingBonusMargin},
+		{req.LosingBonus, &points.LosingBonus},
+		{req.ShootoutWin, &points.ShootoutWin},
+		{req.ShootoutLoss, &points.ShootoutLoss},
+	} {
+		if field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	points.NoDraws = req.NoDraws
+	return points
+}
+
+// playoffSystemFromRequest returns the playoffs of the request, playing single-legged ties by default
+func playoffSystemFromRequest(req *dto.PlayoffSystemRequest) models.PlayoffSystem {
+	if req == nil {
+		return models.PlayoffSystem{}
+	}
+	playoffs := models.PlayoffSystem{
+		Teams:          req.Teams,
+		RelegationFrom: req.RelegationFrom,
+		RelegationTo:   req.RelegationTo,
+		Legs:           utils.MinKnockoutLegs,
+		AwayGoals:      req.AwayGoals,
+	}
+	if req.Legs != nil {
+		playoffs.Legs = *req.Legs
+	}
+	return playoffs
+}
+
 func convertToLeagueResponse(league *models.League) *dto.LeagueResponse {
 	response := &dto.LeagueResponse{
-		ID:        league.ID,
-		Name:      league.Name,
-		TeamCount: league.TeamCount,
-		MaxWeeks:  league.MaxWeeks,
-		CurrWeek:  league.CurrWeek,
-		Teams:     make([]models.Team, len(league.Teams)),
-		Matches:   make([]models.Match, len(league.Matches)),
+		ID:           league.ID,
+		Name:         league.Name,
+		Format:       league.Format,
+		TeamCount:    league.TeamCount,
+		MaxWeeks:     league.MaxWeeks,
+		Legs:         league.Legs,
+		CurrWeek:     league.CurrWeek,
+		Engine:       league.Engine,
+		EngineParams: league.EngineParams,
+		UseRating:    league.UseRating,
+		Seed:         league.Seed,
+
+		SimulationIterations: league.SimulationIterations,
+		TargetStdErr:         league.TargetStdErr,
+		EstimationStartWeek:  league.EstimationStartWeek,
+
+		TieBreakers: league.TieBreakers,
+		Points:      league.Points,
+		Playoffs:    league.Playoffs,
+
+		CompetitionID: league.CompetitionID,
+		Season:        league.Season,
+
+		Teams:   make([]models.Team, len(league.Teams)),
+		Matches: make([]models.Match, len(league.Matches)),
+		Byes:    league.Byes,
 	}
 
 	for i, team := range league.Teams {
 		response.Teams[i] = models.Team{
 			ID:       team.ID,
 			LeagueID: team.LeagueID,
+			ClubID:   team.ClubID,
 			Name:     team.Name,
 			Strength: team.Strength,
+			Rating:   team.Rating,
 			Stats:    team.Stats,
 		}
 	}
@@ -107,17 +347,62 @@ func convertToLeagueResponse(league *models.League) *dto.LeagueResponse {
 			HomeScore:  match.HomeScore,
 			AwayScore:  match.AwayScore,
 			Played:     match.Played,
+			Seed:       match.Seed,
+			Events:     match.Events,
+
+			HomePenalties: match.HomePenalties,
+			AwayPenalties: match.AwayPenalties,
 		}
 	}
 
 	return response
 }
 
-func (s *LeagueService) SimulateWeek(leagueID uint) (*dto.Week, error) {
+// SimulateWeek plays the current week of the league, or the current round of its playoffs. The
+// week is committed as a whole: a failure leaves the league as it was before the week. The
+// estimations after the week are published once it is committed. An expected week other than the
+// current one fails with a conflict, as does losing the week to a concurrent request.
+func (s *LeagueService) SimulateWeek(leagueID uint, expectedWeek *int) (*dto.Week, error) {
+	week, err := s.playWeek(leagueID, expectedWeek)
+	if err != nil {
+		return nil, err
+	}
+	return s.withEstimations(leagueID, week), nil
+}
+
+// PlayWeek plays the current week like SimulateWeek but leaves its estimations to
+// PublishEstimations, for callers committing the week as part of a larger unit of work
+func (s *LeagueService) PlayWeek(leagueID uint) (*dto.Week, error) {
+	return s.playWeek(leagueID, nil)
+}
+
+func (s *LeagueService) playWeek(leagueID uint, expectedWeek *int) (*dto.Week, error) {
+	var week *dto.Week
+	err := s.inTransaction(func(tx *LeagueService) error {
+		var err error
+		week, err = tx.simulateWeek(leagueID, expectedWeek)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return week, nil
+}
+
+func (s *LeagueService) simulateWeek(leagueID uint, expectedWeek *int) (*dto.Week, error) {
 	league, err := s.repo.GetLeagueByID(leagueID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get league with ID %d: %w", leagueID, err)
 	}
+	if expectedWeek != nil && *expectedWeek != league.CurrWeek {
+		return nil, fmt.Errorf("league %d is at week %d, not week %d: %w", leagueID, league.CurrWeek, *expectedWeek, repository.ErrLeagueConflict)
+	}
+	if err := requireLeagueFormat(league); err != nil {
+		return nil, err
+	}
+	if league.CurrWeek > league.MaxWeeks {
+		return s.simulatePlayoffRound(league)
+	}
 
 	// Get matches for the current week
 	matches, err := s.repo.GetMatchesByLeagueIdAndWeek(leagueID, league.CurrWeek)
@@ -129,279 +414,665 @@ func (s *LeagueService) SimulateWeek(leagueID uint) (*dto.Week, error) {
 		return nil, fmt.Errorf("no matches found for league %d and week %d", leagueID, league.CurrWeek)
 	}
 
+	// Claim the week before playing it
+	updatedLeague, err := s.claimWeek(league)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := utils.NewMatchEngine(*league)
+	if err != nil {
+		return nil, err
+	}
+	rules := utils.NewRules(*league)
+
 	// Play all matches for the current week
 	for i, match := range matches {
 		if !match.Played {
-			simulatedMatch, err := s.matchService.SimulateMatch(match)
+			simulatedMatch, err := s.matchService.SimulateMatch(match, engine, rules)
 			if err != nil {
 				return nil, fmt.Errorf("failed to play match %d: %w", match.ID, err)
 			}
 			matches[i] = simulatedMatch // Update the match in the slice
 		}
 	}
-	// Update championship probabilities if we're past week 3
-	if league.CurrWeek > 3 {
-		if err := s.updateChampionshipProbabilities(leagueID, league.CurrWeek); err != nil {
-			return nil, fmt.Errorf("failed to update championship probabilities: %w", err)
-		}
-	}
 	// Get Team stats after each week
 	newStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get team stats for league %d: %w", leagueID, err)
 	}
+	byes, err := s.matchService.GetByesByLeagueIdAndWeek(leagueID, league.CurrWeek)
+	if err != nil {
+		return nil, err
+	}
 	// Log the weekly results
 	if err := s.weeklyLogRepo.SaveWeeklyLog(leagueID, league.CurrWeek); err != nil {
 		return nil, fmt.Errorf("failed to log weekly results for league %d and week %d: %w", leagueID, league.CurrWeek, err)
 	}
-
-	// Increment the league week
-	updatedLeague, err := s.repo.IncrementWeek(leagueID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to increment league week: %w", err)
+	if err := s.pairSwissRound(league, league.CurrWeek); err != nil {
+		return nil, err
 	}
+
 	if updatedLeague.CurrWeek > updatedLeague.MaxWeeks {
-		champion, err := s.getChampionByLeagueID(leagueID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get champion for league %d: %w", leagueID, err)
-		}
-		return &dto.Week{
+		return s.finishRegularSeason(updatedLeague, &dto.Week{
 			LeagueID:  updatedLeague.ID,
 			Week:      updatedLeague.CurrWeek,
 			Matches:   matches,
+			Byes:      byes,
 			TeamStats: newStats,
-			Champion:  champion,
-		}, nil
+		})
 	}
 
 	return &dto.Week{
 		LeagueID:  updatedLeague.ID,
 		Week:      updatedLeague.CurrWeek,
 		Matches:   matches,
+		Byes:      byes,
 		TeamStats: newStats,
 	}, nil
 }
 
+// PlayRemainingMatches plays the rest of the league, its playoffs included. Every week and every
+// playoff round is committed on its own, so a failure keeps the weeks played before it, and its
+// estimations are published once it is committed.
 func (s *LeagueService) PlayRemainingMatches(leagueID uint) ([]*dto.Week, error) {
 	league, err := s.repo.GetLeagueByID(leagueID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
 	}
+	if err := requireLeagueFormat(league); err != nil {
+		return nil, err
+	}
+
+	engine, err := utils.NewMatchEngine(*league)
+	if err != nil {
+		return nil, err
+	}
+	rules := utils.NewRules(*league)
 
 	var weeks []*dto.Week
 
 	for week := league.CurrWeek; week <= league.MaxWeeks; week++ {
-		matches, err := s.repo.GetMatchesByLeagueIdAndWeek(leagueID, week)
+		var played *dto.Week
+		err := s.inTransaction(func(tx *LeagueService) error {
+			var err error
+			played, err = tx.playRemainingWeek(league, week, engine, rules)
+			return err
+		})
 		if err != nil {
-			return nil, fmt.Errorf("failed to get matches for league %d and week %d: %w", leagueID, week, err)
+			return nil, err
 		}
-
-		if len(matches) == 0 {
-			continue // No matches for this week
+		if played != nil {
+			weeks = append(weeks, s.withEstimations(leagueID, played))
 		}
+	}
 
-		for i, match := range matches {
-			if !match.Played {
-				simulatedMatch, err := s.matchService.SimulateMatch(match)
-				if err != nil {
-					return nil, fmt.Errorf("failed to play match %d: %w", match.ID, err)
-				}
-				matches[i] = simulatedMatch // Update the match in the slice
-			}
-		}
-		// Update championship probabilities if we're past week 3
-		if week > 3 {
-			if err := s.updateChampionshipProbabilities(leagueID, week); err != nil {
-				return nil, fmt.Errorf("failed to update championship probabilities: %w", err)
-			}
-		}
-		// Get Team stats after each week
-		newStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
+	// Play the playoff rounds once the regular season is over
+	for {
+		league, err = s.repo.GetLeagueByID(leagueID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get team stats for league %d: %w", leagueID, err)
-		}
-		if err := s.weeklyLogRepo.SaveWeeklyLog(leagueID, week); err != nil {
-			return nil, fmt.Errorf("failed to log weekly results for league %d and week %d: %w", leagueID, week, err)
+			return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
 		}
-		// Increment the league week
-		if _, err := s.repo.IncrementWeek(leagueID); err != nil {
-			return nil, fmt.Errorf("failed to increment league week: %w", err)
-		}
-		if week == league.MaxWeeks {
-			champion, err := s.getChampionByLeagueID(leagueID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get champion for league %d: %w", leagueID, err)
-			}
-			weeks = append(weeks, &dto.Week{
-				LeagueID:  league.ID,
-				Week:      week,
-				Matches:   matches,
-				TeamStats: newStats,
-				Champion:  champion,
-			})
+		if league.CurrWeek <= league.MaxWeeks || utils.LeagueFinished(*league) {
 			return weeks, nil
-
 		}
-
-		weeks = append(weeks, &dto.Week{
-			LeagueID:  league.ID,
-			Week:      week,
-			Matches:   matches,
-			TeamStats: newStats,
+		var week *dto.Week
+		err := s.inTransaction(func(tx *LeagueService) error {
+			var err error
+			week, err = tx.simulatePlayoffRound(league)
+			return err
 		})
+		if err != nil {
+			return nil, err
+		}
+		weeks = append(weeks, s.withEstimations(leagueID, week))
 	}
-
-	return weeks, nil
 }
 
-// updateChampionshipProbabilities updates the championship probabilities for all teams
-func (s *LeagueService) updateChampionshipProbabilities(leagueID uint, week int) error {
-	currentLeagueState, err := s.populateLeagueState(leagueID, week)
-	if err != nil {
-		return fmt.Errorf("failed to populate league state: %w", err)
-	}
-
-	// Run Monte Carlo simulation
-	results, err := utils.EstimateChampionshipProbabilities(*currentLeagueState)
+// playRemainingWeek plays the given week of the regular season for PlayRemainingMatches, nil when
+// the week has no matches
+func (s *LeagueService) playRemainingWeek(league *models.League, week int, engine utils.MatchEngine, rules utils.Rules) (*dto.Week, error) {
+	leagueID := league.ID
+	matches, err := s.repo.GetMatchesByLeagueIdAndWeek(leagueID, week)
 	if err != nil {
-		return fmt.Errorf("failed to estimate championship probabilities: %w", err)
+		return nil, fmt.Errorf("failed to get matches for league %d and week %d: %w", leagueID, week, err)
 	}
 
-	// Update the estimations in the database
-	if err := s.teamStatsRepo.UpdateChampionshipEstimation(results); err != nil {
-		return fmt.Errorf("failed to update championship estimations: %w", err)
+	if len(matches) == 0 {
+		return nil, nil // No matches for this week
 	}
 
-	return nil
-}
-func (s *LeagueService) populateLeagueState(leagueID uint, week int) (*dto.LeagueState, error) {
-	league, err := s.repo.GetLeagueByID(leagueID)
+	// Claim the week before playing it
+	updatedLeague, err := s.claimWeek(league)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+		return nil, err
 	}
+	league.CurrWeek, league.Version = updatedLeague.CurrWeek, updatedLeague.Version
 
-	matches, err := s.repo.GetRemainingMatches(leagueID, week)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get matches for league %d and week %d: %w", leagueID, week, err)
+	for i, match := range matches {
+		if !match.Played {
+			simulatedMatch, err := s.matchService.SimulateMatch(match, engine, rules)
+			if err != nil {
+				return nil, fmt.Errorf("failed to play match %d: %w", match.ID, err)
+			}
+			matches[i] = simulatedMatch // Update the match in the slice
+		}
 	}
-
-	teams, err := s.repo.GetTeamsByLeagueID(leagueID)
+	// Get Team stats after each week
+	newStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
+		return nil, fmt.Errorf("failed to get team stats for league %d: %w", leagueID, err)
 	}
-	teamStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
+	byes, err := s.matchService.GetByesByLeagueIdAndWeek(leagueID, week)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get team stats for league %d: %w", leagueID, err)
+		return nil, err
 	}
-	// Validate teams
-	// Create a deep copy of the league state to prevent modifications to the original data
-	copiedTeams := make([]models.Team, len(teams))
-	copy(copiedTeams, teams)
-
-	copiedMatches := make([]models.Match, len(matches))
-	copy(copiedMatches, matches)
-
-	copiedTeamStats := make([]models.TeamStats, len(teamStats))
-	copy(copiedTeamStats, teamStats)
-
-	return &dto.LeagueState{
-		LeagueID:         league.ID,
-		Week:             week,
-		RemainingMatches: copiedMatches,
-		Teams:            copiedTeams,
-		TeamStats:        copiedTeamStats,
-	}, nil
+	if err := s.weeklyLogRepo.SaveWeeklyLog(leagueID, week); err != nil {
+		return nil, fmt.Errorf("failed to log weekly results for league %d and week %d: %w", leagueID, week, err)
+	}
+	if err := s.pairSwissRound(league, week); err != nil {
+		return nil, err
+	}
+	played := &dto.Week{
+		LeagueID:  league.ID,
+		Week:      week,
+		Matches:   matches,
+		Byes:      byes,
+		TeamStats: newStats,
+	}
+	if week == league.MaxWeeks {
+		return s.finishRegularSeason(league, played)
+	}
+	return played, nil
 }
-func (s *LeagueService) UserPlayWeek(matches []dto.UserPlayedMatch) (*dto.Week, error) {
-	league, err := s.repo.GetLeagueByID(matches[0].LeagueID)
+
+// claimWeek moves the league to its next week before the week is played. A concurrent request for
+// the same week waits for the lock on the league row and then fails with a conflict, before
+// writing anything.
+func (s *LeagueService) claimWeek(league *models.League) (*models.League, error) {
+	updatedLeague, err := s.repo.IncrementWeek(league.ID, league.Version)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get league by ID %d: %w", matches[0].LeagueID, err)
-	}
-	// Validate matches
-	for _, match := range matches {
-		if match.HomeScore < 0 || match.AwayScore < 0 {
-			return nil, fmt.Errorf("scores cannot be negative")
-		}
-		if match.HomeTeamID == match.AwayTeamID {
-			return nil, fmt.Errorf("home and away teams cannot be the same")
-		}
+		return nil, fmt.Errorf("failed to increment league week: %w", err)
 	}
+	return updatedLeague, nil
+}
 
-	// Play matches
-	var playedMatches []models.Match
-	for _, userMatch := range matches {
-		playedMatch, err := s.matchService.UserPlayMatch(userMatch)
+// finishRegularSeason completes the week that ended the regular season with the champion, or
+// with the ties of the first playoff round when the league plays playoffs
+func (s *LeagueService) finishRegularSeason(league *models.League, week *dto.Week) (*dto.Week, error) {
+	if !utils.HasPlayoffs(league.Playoffs) {
+		champion, err := s.getChampionByLeagueID(league.ID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to play match %d: %w", userMatch.MatchID, err)
+			return nil, fmt.Errorf("failed to get champion for league %d: %w", league.ID, err)
 		}
-		playedMatches = append(playedMatches, playedMatch)
+		week.Champion = champion
+		return week, nil
 	}
 
-	if league.CurrWeek > 3 {
-		if err := s.updateChampionshipProbabilities(league.ID, league.CurrWeek); err != nil {
-			return nil, fmt.Errorf("failed to update championship probabilities: %w", err)
-		}
+	if err := s.drawPlayoffs(league); err != nil {
+		return nil, err
 	}
-	// Get Team stats after each week
-	newStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(matches[0].LeagueID)
+	var err error
+	if week.Ties, err = s.playoffTies(league.ID, 1); err != nil {
+		return nil, err
+	}
+	return week, nil
+}
+
+// drawPlayoffs draws the first round of the playoffs from the final table of the regular season
+func (s *LeagueService) drawPlayoffs(league *models.League) error {
+	ranked, err := s.rankLeague(league)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get team stats for league %d: %w", matches[0].LeagueID, err)
+		return err
 	}
+	ties := utils.DrawPlayoffs(league.Playoffs, ranked)
+	if _, err := s.tieRepo.CreateRound(*league, utils.NewPlayoffRules(league.Playoffs), ties, utils.PlayoffFirstWeek(*league, 1)); err != nil {
+		return fmt.Errorf("failed to draw the playoffs of league %d: %w", league.ID, err)
+	}
+	return nil
+}
 
-	// Log the weekly results
-	if err := s.weeklyLogRepo.SaveWeeklyLog(matches[0].LeagueID, league.CurrWeek); err != nil {
-		return nil, fmt.Errorf("failed to log weekly results for league %d and week %d: %w", matches[0].LeagueID, league.CurrWeek, err)
+// simulatePlayoffRound plays the current round of the title and relegation playoffs
+func (s *LeagueService) simulatePlayoffRound(league *models.League) (*dto.Week, error) {
+	if utils.LeagueFinished(*league) {
+		return nil, fmt.Errorf("league %d is already finished", league.ID)
 	}
+	rules := utils.NewPlayoffRules(league.Playoffs)
+	round := utils.PlayoffRound(*league, league.CurrWeek)
+	nextWeek := utils.PlayoffFirstWeek(*league, round+1)
 
-	// Increment the league week
-	updatedLeague, err := s.repo.IncrementWeek(matches[0].LeagueID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to increment league week: %w", err)
+	// Claim the round before playing it, every leg takes a week of the league calendar
+	updatedLeague := league
+	for leg := 0; leg < rules.Legs; leg++ {
+		var err error
+		if updatedLeague, err = s.claimWeek(updatedLeague); err != nil {
+			return nil, err
+		}
 	}
-	if updatedLeague.CurrWeek > updatedLeague.MaxWeeks {
-		champion, err := s.getChampionByLeagueID(matches[0].LeagueID)
+
+	week := &dto.Week{LeagueID: league.ID}
+	for _, stage := range []struct {
+		name   string
+		rounds int
+	}{
+		{utils.StagePlayoff, utils.TitlePlayoffRounds(league.Playoffs)},
+		{utils.StageRelegation, utils.RelegationPlayoffRounds(league.Playoffs)},
+	} {
+		if round > stage.rounds {
+			continue
+		}
+		ties, err := s.cupService.PlayRound(*league, stage.name, round, stage.rounds, rules, nextWeek)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get champion for league %d: %w", matches[0].LeagueID, err)
+			return nil, fmt.Errorf("failed to play round %d of the %s playoff: %w", round, stage.name, err)
+		}
+		for _, tie := range ties {
+			week.Matches = append(week.Matches, tie.Matches...)
+		}
+		week.Ties = append(week.Ties, ties...)
+		if stage.name == utils.StageRelegation && round == stage.rounds {
+			if err := s.relegationOutcome(league, ties, week); err != nil {
+				return nil, err
+			}
 		}
-		return &dto.Week{
-			LeagueID:  updatedLeague.ID,
-			Week:      updatedLeague.CurrWeek,
-			Matches:   playedMatches,
-			TeamStats: newStats,
-			Champion:  champion,
-		}, nil
-
 	}
 
-	return &dto.Week{
-		LeagueID:  updatedLeague.ID,
-		Week:      updatedLeague.CurrWeek,
-		Matches:   playedMatches,
-		TeamStats: newStats,
-	}, nil
-}
+	week.Week = updatedLeague.CurrWeek
 
-func (s *LeagueService) GetChampionshipEstimationByLeagueID(leagueID uint) ([]dto.ChampionshipEstimation, error) {
-	league, err := s.repo.GetLeagueByID(leagueID)
+	newStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(league.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+		return nil, fmt.Errorf("failed to get team stats for league %d: %w", league.ID, err)
 	}
-
-	if league.CurrWeek <= 3 {
-		return nil, fmt.Errorf("championship estimation is only available after week 3")
+	week.TeamStats = newStats
+	if utils.LeagueFinished(*updatedLeague) {
+		if week.Champion, err = s.getChampionByLeagueID(league.ID); err != nil {
+			return nil, fmt.Errorf("failed to get champion for league %d: %w", league.ID, err)
+		}
 	}
+	return week, nil
+}
 
-	teams, err := s.repo.GetTeamsByLeagueID(leagueID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
+// relegationOutcome completes the week in which the final of the relegation playoff was played
+// with its survivor and the teams it relegates, every other team of the playoff
+func (s *LeagueService) relegationOutcome(league *models.League, final []models.Tie, week *dto.Week) error {
+	survivor := utils.PlayoffWinner(final, utils.RelegationPlayoffRounds(league.Playoffs))
+	if survivor == nil {
+		return fmt.Errorf("the relegation playoff final of league %d has no winner", league.ID)
 	}
-	if len(teams) == 0 {
-		return nil, fmt.Errorf("no teams found for league %d", leagueID)
+	ties, err := s.tieRepo.GetTiesByLeagueID(league.ID, utils.StageRelegation)
+	if err != nil {
+		return err
+	}
+	seen := map[uint]bool{*survivor: true}
+	for _, tie := range ties {
+		for _, teamID := range []*uint{tie.HomeTeamID, tie.AwayTeamID} {
+			if teamID == nil || seen[*teamID] {
+				continue
+			}
+			seen[*teamID] = true
+			team, err := s.teamRepo.GetTeamByID(*teamID)
+			if err != nil {
+				return fmt.Errorf("failed to get relegated team by ID %d: %w", *teamID, err)
+			}
+			week.Relegated = append(week.Relegated, team)
+		}
+	}
+	team, err := s.teamRepo.GetTeamByID(*survivor)
+	if err != nil {
+		return fmt.Errorf("failed to get relegation playoff winner by ID %d: %w", *survivor, err)
+	}
+	week.RelegationSurvivor = &team
+	return nil
+}
+
+// playoffTies returns the ties of a round of the title and relegation playoffs
+func (s *LeagueService) playoffTies(leagueID uint, round int) ([]models.Tie, error) {
+	var ties []models.Tie
+	for _, stage := range []string{utils.StagePlayoff, utils.StageRelegation} {
+		stageTies, err := s.tieRepo.GetTiesByLeagueIDAndRound(leagueID, stage, round)
+		if err != nil {
+			return nil, err
+		}
+		ties = append(ties, stageTies...)
+	}
+	return ties, nil
+}
+
+// pairSwissRound pairs the round of a Swiss-system league following the week just played from the
+// standings after it. The schedules of the other formats are generated when the league is created.
+func (s *LeagueService) pairSwissRound(league *models.League, week int) error {
+	if league.Format != utils.FormatSwiss || week >= league.MaxWeeks {
+		return nil
+	}
+	ranked, err := s.rankLeague(league)
+	if err != nil {
+		return err
+	}
+	teamIDs := make([]uint, len(ranked))
+	for i, stat := range ranked {
+		teamIDs[i] = stat.TeamID
+	}
+	if _, _, err := s.matchService.ScheduleSwissRound(*league, week+1, teamIDs); err != nil {
+		return err
+	}
+	return nil
+}
+
+// requireLeagueFormat fails for knockout competitions, which are played through the cup endpoints
+func requireLeagueFormat(league *models.League) error {
+	if league.Format == utils.FormatKnockout {
+		return fmt.Errorf("competition %d is a knockout cup, use the cup endpoints", league.ID)
+	}
+	return nil
+}
+
+// estimationsAvailable reports whether the league publishes estimations once weeksPlayed weeks are played.
+// A start week of 0 publishes pre-season projections driven by the team strengths alone.
+func estimationsAvailable(league *models.League, weeksPlayed int) bool {
+	return weeksPlayed >= league.EstimationStartWeek
+}
+
+// updateChampionshipProbabilities updates the championship probabilities for all teams
+func (s *LeagueService) updateChampionshipProbabilities(league *models.League, week int) error {
+	projection, err := s.estimateChampionship(league, week)
+	if err != nil {
+		return err
+	}
+	return s.storeEstimations(projection)
+}
+
+// estimateChampionship runs the Monte Carlo estimation of the league after the given week
+func (s *LeagueService) estimateChampionship(league *models.League, week int) (*dto.LeagueProjection, error) {
+	currentLeagueState, err := s.populateLeagueState(league, week)
+	if err != nil {
+		return nil, fmt.Errorf("failed to populate league state: %w", err)
+	}
+
+	engine, err := utils.NewMatchEngine(*league)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run Monte Carlo simulation
+	projection, err := utils.EstimateChampionshipProbabilities(*currentLeagueState, engine, utils.NewRules(*league), utils.NewSimulationOptions(*league, week))
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate championship probabilities: %w", err)
+	}
+	return projection, nil
+}
+
+// storeEstimations saves the estimations of a projection and the projection itself
+func (s *LeagueService) storeEstimations(projection *dto.LeagueProjection) error {
+	if err := s.teamStatsRepo.UpdateChampionshipEstimation(projection.Estimations); err != nil {
+		return fmt.Errorf("failed to update championship estimations: %w", err)
+	}
+	if err := s.projectionRepo.SaveProjection(*projection); err != nil {
+		return fmt.Errorf("failed to save projection: %w", err)
+	}
+	return nil
+}
+
+// PublishEstimations estimates the league after its last week played once that week is committed.
+// The Monte Carlo runs outside any transaction so it holds no locks; its estimations, projection
+// and the weekly log of the week are then stored in a short transaction, and dropped when another
+// request changed the league meanwhile.
+func (s *LeagueService) PublishEstimations(leagueID uint) error {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	week := league.CurrWeek - 1
+	if !estimationsAvailable(league, week) {
+		return nil
+	}
+	projection, err := s.estimateChampionship(league, week)
+	if err != nil {
+		return err
+	}
+
+	return s.inTransaction(func(tx *LeagueService) error {
+		current, err := tx.repo.LockLeague(leagueID)
+		if err != nil {
+			return err
+		}
+		if current.Version != league.Version {
+			fmt.Printf("League %d changed while estimating week %d, estimations dropped\n", leagueID, week)
+			return nil
+		}
+		if err := tx.storeEstimations(projection); err != nil {
+			return err
+		}
+		// The weekly log of a regular season week holds the estimations after it
+		if week >= 1 && week <= league.MaxWeeks {
+			if err := tx.weeklyLogRepo.ReplaceWeeklyLog(leagueID, week); err != nil {
+				return fmt.Errorf("failed to log weekly results for league %d and week %d: %w", leagueID, week, err)
+			}
+		}
+		return nil
+	})
+}
+
+// republishEstimations publishes the estimations after the last week played again, for a league
+// whose history was rewritten. A failure leaves the estimations empty until the next week is played.
+func (s *LeagueService) republishEstimations(leagueID uint) {
+	if err := s.PublishEstimations(leagueID); err != nil {
+		fmt.Printf("failed to publish estimations of league %d: %v\n", leagueID, err)
+	}
+}
+
+// withEstimations publishes the estimations after the week just committed and returns the week
+// with the team stats carrying them. The week stays played when they cannot be published.
+func (s *LeagueService) withEstimations(leagueID uint, week *dto.Week) *dto.Week {
+	if err := s.PublishEstimations(leagueID); err != nil {
+		fmt.Printf("failed to publish estimations of league %d: %v\n", leagueID, err)
+		return week
+	}
+	stats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
+	if err != nil {
+		fmt.Printf("failed to get team stats for league %d: %v\n", leagueID, err)
+		return week
+	}
+	week.TeamStats = stats
+	return week
+}
+
+// GetLeagueState returns the state of the league after the weeks played so far
+func (s *LeagueService) GetLeagueState(leagueID uint) (*dto.LeagueState, error) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	return s.populateLeagueState(league, league.CurrWeek-1)
+}
+
+func (s *LeagueService) populateLeagueState(league *models.League, week int) (*dto.LeagueState, error) {
+	leagueID := league.ID
+	matches, err := s.repo.GetRemainingMatches(leagueID, week)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get matches for league %d and week %d: %w", leagueID, week, err)
+	}
+
+	teams, err := s.repo.GetTeamsByLeagueID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
+	}
+	teamStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team stats for league %d: %w", leagueID, err)
+	}
+	playedMatches, err := s.repo.GetPlayedMatches(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	var playoffTies, relegationTies []models.Tie
+	if league.Playoffs.Teams > 0 {
+		if playoffTies, err = s.tieRepo.GetTiesByLeagueID(leagueID, utils.StagePlayoff); err != nil {
+			return nil, err
+		}
+	}
+	if league.Playoffs.RelegationFrom > 0 {
+		if relegationTies, err = s.tieRepo.GetTiesByLeagueID(leagueID, utils.StageRelegation); err != nil {
+			return nil, err
+		}
+	}
+	// Validate teams
+	// Create a deep copy of the league state to prevent modifications to the original data
+	copiedTeams := make([]models.Team, len(teams))
+	copy(copiedTeams, teams)
+
+	copiedMatches := make([]models.Match, len(matches))
+	copy(copiedMatches, matches)
+
+	copiedTeamStats := make([]models.TeamStats, len(teamStats))
+	copy(copiedTeamStats, teamStats)
+
+	return &dto.LeagueState{
+		LeagueID:         league.ID,
+		Week:             week,
+		RemainingMatches: copiedMatches,
+		PlayedMatches:    playedMatches,
+		Teams:            copiedTeams,
+		TeamStats:        copiedTeamStats,
+		PlayoffTies:      playoffTies,
+		RelegationTies:   relegationTies,
+		PlayoffRound:     utils.PlayoffRound(*league, week+1),
+	}, nil
+}
+
+// UserPlayWeek records the results of the current week entered by hand, committed as a whole
+func (s *LeagueService) UserPlayWeek(matches []dto.UserPlayedMatch) (*dto.Week, error) {
+	var week *dto.Week
+	err := s.inTransaction(func(tx *LeagueService) error {
+		var err error
+		week, err = tx.userPlayWeek(matches)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.withEstimations(matches[0].LeagueID, week), nil
+}
+
+func (s *LeagueService) userPlayWeek(matches []dto.UserPlayedMatch) (*dto.Week, error) {
+	if len(matches) == 0 {
+		return nil, &helpers.ValidationError{Field: "matches", Message: "at least one match result is required"}
+	}
+	league, err := s.repo.GetLeagueByID(matches[0].LeagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", matches[0].LeagueID, err)
+	}
+	if err := requireLeagueFormat(league); err != nil {
+		return nil, err
+	}
+	if league.CurrWeek > league.MaxWeeks {
+		return nil, fmt.Errorf("the regular season of league %d is over, playoff rounds are simulated", league.ID)
+	}
+	// Validate matches
+	for _, match := range matches {
+		if match.HomeScore < 0 || match.AwayScore < 0 {
+			return nil, fmt.Errorf("scores cannot be negative")
+		}
+		if match.HomeTeamID == match.AwayTeamID {
+			return nil, fmt.Errorf("home and away teams cannot be the same")
+		}
+		if err := helpers.ValidatePenalties(match, league.Points.NoDraws); err != nil {
+			return nil, err
+		}
+	}
+	fixtures, err := s.matchService.GetMatchesByLeagueIdAndWeek(league.ID, league.CurrWeek)
+	if err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateWeekResults(*league, fixtures, matches); err != nil {
+		return nil, err
+	}
+	rules := utils.NewRules(*league)
+
+	// Claim the week before playing it
+	updatedLeague, err := s.claimWeek(league)
+	if err != nil {
+		return nil, err
+	}
+
+	// Play matches
+	var playedMatches []models.Match
+	for _, userMatch := range matches {
+		playedMatch, err := s.matchService.UserPlayMatch(userMatch, rules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to play match %d: %w", userMatch.MatchID, err)
+		}
+		playedMatches = append(playedMatches, playedMatch)
+	}
+
+	// Get Team stats after each week
+	newStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(matches[0].LeagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team stats for league %d: %w", matches[0].LeagueID, err)
+	}
+	byes, err := s.matchService.GetByesByLeagueIdAndWeek(matches[0].LeagueID, league.CurrWeek)
+	if err != nil {
+		return nil, err
+	}
+
+	// Log the weekly results
+	if err := s.weeklyLogRepo.SaveWeeklyLog(matches[0].LeagueID, league.CurrWeek); err != nil {
+		return nil, fmt.Errorf("failed to log weekly results for league %d and week %d: %w", matches[0].LeagueID, league.CurrWeek, err)
+	}
+	if err := s.pairSwissRound(league, league.CurrWeek); err != nil {
+		return nil, err
+	}
+
+	if updatedLeague.CurrWeek > updatedLeague.MaxWeeks {
+		return s.finishRegularSeason(updatedLeague, &dto.Week{
+			LeagueID:  updatedLeague.ID,
+			Week:      updatedLeague.CurrWeek,
+			Matches:   playedMatches,
+			Byes:      byes,
+			TeamStats: newStats,
+		})
+	}
+
+	return &dto.Week{
+		LeagueID:  updatedLeague.ID,
+		Week:      updatedLeague.CurrWeek,
+		Matches:   playedMatches,
+		Byes:      byes,
+		TeamStats: newStats,
+	}, nil
+}
+
+func (s *LeagueService) GetChampionshipEstimationByLeagueID(leagueID uint, req dto.EstimationRequest) ([]dto.ChampionshipEstimation, error) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+
+	if err := requireLeagueFormat(league); err != nil {
+		return nil, err
+	}
+	if !estimationsAvailable(league, league.CurrWeek-1) {
+		return nil, fmt.Errorf("championship estimation is only available after week %d", league.EstimationStartWeek)
+	}
+
+	// Custom settings are estimated on demand instead of returning the stored estimations
+	if req.Iterations != 0 || req.StdErr != 0 {
+		projection, err := s.estimateWithSettings(league, req)
+		if err != nil {
+			return nil, err
+		}
+		return projection.Estimations, nil
+	}
+
+	teams, err := s.repo.GetTeamsByLeagueID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
+	}
+	if len(teams) == 0 {
+		return nil, fmt.Errorf("no teams found for league %d", leagueID)
 	}
 
 	var estimations []dto.ChampionshipEstimation
 	for _, team := range teams {
-		estimation, err := s.teamStatsRepo.GetChampionshipEstimationByTeamID(team.ID)
+		stats, err := s.teamStatsRepo.GetTeamStatsByTeamID(team.ID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get championship estimation for team %d: %w", team.ID, err)
 		}
@@ -409,24 +1080,155 @@ func (s *LeagueService) GetChampionshipEstimationByLeagueID(leagueID uint) ([]dt
 			TeamID:     team.ID,
 			Week:       league.CurrWeek,
 			LeagueID:   league.ID,
-			Estimation: estimation,
+			Estimation: stats.Estimation,
+			Iterations: stats.EstimationIterations,
+			StdErr:     stats.EstimationStdErr,
 		})
 
 	}
 
+	// Guarantees are derived from the current standings so they are always up to date
+	state, err := s.populateLeagueState(league, league.CurrWeek-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to populate league state: %w", err)
+	}
+	utils.ApplyGuarantees(estimations, utils.LeagueGuarantees(*state, utils.NewRules(*league)))
+
 	return estimations, nil
 }
-func (s *LeagueService) getChampionByLeagueID(leagueID uint) (team models.Team, err error) {
-	teamStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
+
+// EstimateProjection runs a fresh estimation of the current state of the league, with the
+// requested Monte Carlo settings overriding the league's
+func (s *LeagueService) EstimateProjection(leagueID uint, req dto.EstimationRequest) (*dto.LeagueProjection, error) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	if err := requireLeagueFormat(league); err != nil {
+		return nil, err
+	}
+	return s.estimateWithSettings(league, req)
+}
+
+// estimateWithSettings runs a fresh estimation of the current state with the requested Monte Carlo settings
+func (s *LeagueService) estimateWithSettings(league *models.League, req dto.EstimationRequest) (*dto.LeagueProjection, error) {
+	if err := helpers.ValidateSimulationSettings(req.Iterations, req.StdErr); err != nil {
+		return nil, err
+	}
+
+	week := league.CurrWeek - 1
+	state, err := s.populateLeagueState(league, week)
+	if err != nil {
+		return nil, fmt.Errorf("failed to populate league state: %w", err)
+	}
+	engine, err := utils.NewMatchEngine(*league)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := utils.NewSimulationOptions(*league, week)
+	if req.Iterations != 0 {
+		opts.Iterations = req.Iterations
+	}
+	if req.StdErr != 0 {
+		opts.TargetStdErr = req.StdErr
+	}
+
+	projection, err := utils.EstimateChampionshipProbabilities(*state, engine, utils.NewRules(*league), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate championship probabilities: %w", err)
+	}
+	return projection, nil
+}
+
+// GetProjections returns the stored projection of the given week or the latest one when week is nil
+func (s *LeagueService) GetProjections(leagueID uint, week *int) (*dto.LeagueProjection, error) {
+	if _, err := s.repo.GetLeagueByID(leagueID); err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	if week != nil {
+		return s.projectionRepo.GetProjectionByLeagueIDAndWeek(leagueID, *week)
+	}
+	return s.projectionRepo.GetLatestProjection(leagueID)
+}
+
+// GetStandings returns the current league table ranked with the league's tie-breakers
+func (s *LeagueService) GetStandings(leagueID uint) (*dto.Standings, error) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	ranked, err := s.rankLeague(league)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.repo.GetTeamsByLeagueID(leagueID)
 	if err != nil {
-		return models.Team{}, fmt.Errorf("failed to get team Stats for league %d: %w", leagueID, err)
+		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
+	}
+	teamNames := make(map[uint]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.ID] = team.Name
 	}
 
+	rules := utils.NewRules(*league)
+	standings := &dto.Standings{
+		LeagueID:    league.ID,
+		Week:        min(league.CurrWeek-1, league.MaxWeeks), // playoff weeks leave the table unchanged
+		TieBreakers: rules.TieBreakers,
+		LotsSeed:    rules.LotsSeed,
+		Table:       make([]dto.StandingEntry, len(ranked)),
+	}
+	for i, stat := range ranked {
+		standings.Table[i] = dto.StandingEntry{
+			Position:  i + 1,
+			TeamName:  teamNames[stat.TeamID],
+			TeamStats: stat,
+		}
+	}
+	return standings, nil
+}
+
+// rankLeague ranks the current team stats of the league with its tie-breakers
+func (s *LeagueService) rankLeague(league *models.League) ([]models.TeamStats, error) {
+	teamStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(league.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get team Stats for league %d: %w", league.ID, err)
+	}
 	if len(teamStats) == 0 {
-		return models.Team{}, fmt.Errorf("no teams found for league %d", leagueID)
+		return nil, fmt.Errorf("no teams found for league %d", league.ID)
+	}
+	playedMatches, err := s.repo.GetPlayedMatches(league.ID)
+	if err != nil {
+		return nil, err
 	}
+	return utils.RankStandings(teamStats, playedMatches, utils.NewRules(*league)), nil
+}
 
-	champID := utils.DetermineChampion(teamStats)
+func (s *LeagueService) getChampionByLeagueID(leagueID uint) (team models.Team, err error) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return models.Team{}, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	var champID uint
+	if rounds := utils.TitlePlayoffRounds(league.Playoffs); rounds > 0 {
+		// The title goes to the winner of the playoff final
+		ties, err := s.tieRepo.GetTiesByLeagueIDAndRound(leagueID, utils.StagePlayoff, rounds)
+		if err != nil {
+			return models.Team{}, err
+		}
+		winner := utils.PlayoffWinner(ties, rounds)
+		if winner == nil {
+			return models.Team{}, fmt.Errorf("the playoff final of league %d is not played yet", leagueID)
+		}
+		champID = *winner
+	} else {
+		ranked, err := s.rankLeague(league)
+		if err != nil {
+			return models.Team{}, err
+		}
+		champID = ranked[0].TeamID
+	}
 	champion, err := s.teamRepo.GetTeamByID(champID)
 	if err != nil {
 		return models.Team{}, fmt.Errorf("failed to get champion team by ID %d: %w", champID, err)
@@ -434,3 +1236,588 @@ func (s *LeagueService) getChampionByLeagueID(leagueID uint) (team models.Team,
 
 	return champion, nil
 }
+
+// NextSeason creates the season following a finished league with the same clubs and settings. With
+// a strength drift the clubs' strengths move with their final position; their Elo ratings carry over.
+func (s *LeagueService) NextSeason(leagueID uint, req dto.NextSeasonRequest) (*dto.LeagueResponse, error) {
+	if err := helpers.ValidateStrengthDrift(req.StrengthDrift); err != nil {
+		return nil, err
+	}
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	if err := requireLeagueFormat(league); err != nil {
+		return nil, err
+	}
+	if league.TournamentID != nil || league.PyramidID != nil {
+		return nil, fmt.Errorf("league %d is part of a tournament or pyramid, its seasons are managed there", leagueID)
+	}
+	if !utils.LeagueFinished(*league) {
+		return nil, fmt.Errorf("league %d is still being played", leagueID)
+	}
+
+	competitionID := utils.CompetitionRoot(*league)
+	seasons, err := s.repo.GetCompetitionSeasons(competitionID)
+	if err != nil {
+		return nil, err
+	}
+	season := max(league.Season, utils.FirstSeason) + 1
+	for _, existing := range seasons {
+		if existing.Season >= season {
+			return nil, fmt.Errorf("season %d of competition %d already exists", season, competitionID)
+		}
+	}
+
+	ranked, err := s.rankLeague(league)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.repo.GetTeamsByLeagueID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams for league %d: %w", leagueID, err)
+	}
+	teamsByID := make(map[uint]models.Team, len(teams))
+	for _, team := range teams {
+		teamsByID[team.ID] = team
+	}
+
+	next := &models.League{
+		Name:         league.Name,
+		Format:       league.Format,
+		TeamCount:    league.TeamCount,
+		MaxWeeks:     league.MaxWeeks,
+		Legs:         league.Legs,
+		Engine:       league.Engine,
+		EngineParams: league.EngineParams,
+		UseRating:    league.UseRating,
+		Seed:         utils.NextSeasonSeed(*league),
+
+		SimulationIterations: league.SimulationIterations,
+		TargetStdErr:         league.TargetStdErr,
+		EstimationStartWeek:  league.EstimationStartWeek,
+
+		CompetitionID: &competitionID,
+		Season:        season,
+
+		TieBreakers: league.TieBreakers,
+		Points:      league.Points,
+		Playoffs:    league.Playoffs,
+
+		Teams: make([]models.Team, len(ranked)),
+	}
+	for pos, stat := range ranked {
+		team := teamsByID[stat.TeamID]
+		next.Teams[pos] = models.Team{
+			ClubID:   team.ClubID,
+			Name:     team.Name,
+			Strength: utils.StrengthDrift(team.Strength, pos+1, len(ranked), req.StrengthDrift),
+			Rating:   team.Rating,
+		}
+	}
+	return s.createLeague(next)
+}
+
+// GetAllTimeTable sums every season of the league's competition club by club, ranked by points,
+// then goal difference and goals scored
+func (s *LeagueService) GetAllTimeTable(leagueID uint) (*dto.AllTimeTable, error) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	competitionID := utils.CompetitionRoot(*league)
+	seasons, err := s.repo.GetCompetitionSeasons(competitionID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.clubRepo.GetAllTimeStats(competitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	titles := make(map[uint]int)
+	for _, season := range seasons {
+		if !utils.LeagueFinished(season) {
+			continue
+		}
+		champion, err := s.getChampionByLeagueID(season.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get champion of season %d: %w", season.Season, err)
+		}
+		if champion.ClubID != nil {
+			titles[*champion.ClubID]++
+		}
+	}
+	for i := range entries {
+		entries[i].Titles = titles[entries[i].ClubID]
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Points != entries[j].Points {
+			return entries[i].Points > entries[j].Points
+		}
+		if entries[i].GoalDiff != entries[j].GoalDiff {
+			return entries[i].GoalDiff > entries[j].GoalDiff
+		}
+		return entries[i].GoalsFor > entries[j].GoalsFor
+	})
+	for i := range entries {
+		entries[i].Position = i + 1
+	}
+
+	return &dto.AllTimeTable{
+		CompetitionID: competitionID,
+		Seasons:       len(seasons),
+		Table:         entries,
+	}, nil
+}
+
+// RewindLeague takes the league back to the end of the given week of its regular season, 0 for the
+// start of the season, so the rest of the season can be replayed. The team stats are restored from
+// the weekly log of the week and the ratings from the rating history. When the week ends the
+// regular season the playoffs are drawn again. A new seed, given or random, becomes the seed of
+// the league and the matches after the week are seeded from it, so the replay plays out differently.
+func (s *LeagueService) RewindLeague(leagueID uint, req dto.RewindRequest) (*dto.Standings, error) {
+	seed := req.Seed
+	if seed == nil && req.Reseed {
+		generated := utils.GenerateSeed()
+		seed = &generated
+	}
+	// The rewind and the new draw of the playoffs are committed together
+	if err := s.inTransaction(func(tx *LeagueService) error {
+		return tx.rewindLeague(leagueID, req.Week, seed)
+	}); err != nil {
+		return nil, err
+	}
+	// The weekly logs hold the estimations of the weeks played, the pre-season ones are estimated again
+	if req.Week == 0 {
+		s.republishEstimations(leagueID)
+	}
+	return s.GetStandings(leagueID)
+}
+
+func (s *LeagueService) rewindLeague(leagueID uint, week int, seed *int64) error {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	if err := s.requireRewritableHistory(league); err != nil {
+		return err
+	}
+	if league.CurrWeek <= 1 {
+		return fmt.Errorf("league %d has not played a week yet", leagueID)
+	}
+	// Playoff weeks have no weekly log, the latest week to rewind to is the end of the regular season
+	latest := min(league.CurrWeek-2, league.MaxWeeks)
+	if week < 0 || week > latest {
+		return &helpers.ValidationError{
+			Field:   "week",
+			Message: fmt.Sprintf("must be between 0 and %d", latest),
+		}
+	}
+
+	stats, err := s.weekSnapshot(league, week)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.RewindLeague(*league, week, stats, repository.AuditRewind, seed); err != nil {
+		return fmt.Errorf("failed to rewind league %d to week %d: %w", leagueID, week, err)
+	}
+	league.CurrWeek = week + 1
+	if seed != nil {
+		league.Seed = *seed
+	}
+
+	if week == league.MaxWeeks && utils.HasPlayoffs(league.Playoffs) {
+		if err := s.drawPlayoffs(league); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UndoWeek reverts the last week of the regular season played. The results of its matches are
+// taken out of the team stats and cleared, the estimations go back to their values before the week
+// and its weekly log is deleted. Playoff rounds cannot be undone, the league is rewound instead.
+func (s *LeagueService) UndoWeek(leagueID uint) (*dto.Standings, error) {
+	var week int
+	if err := s.inTransaction(func(tx *LeagueService) error {
+		var err error
+		week, err = tx.undoWeek(leagueID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	// The pre-season estimations are not logged, they are estimated again
+	if week == 1 {
+		s.republishEstimations(leagueID)
+	}
+	return s.GetStandings(leagueID)
+}
+
+// undoWeek reverts the last week played and returns it
+func (s *LeagueService) undoWeek(leagueID uint) (int, error) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	if err := s.requireRewritableHistory(league); err != nil {
+		return 0, err
+	}
+	week := league.CurrWeek - 1
+	if week < 1 {
+		return 0, fmt.Errorf("league %d has not played a week yet", leagueID)
+	}
+	if week > league.MaxWeeks {
+		return 0, fmt.Errorf("playoff rounds cannot be undone, rewind league %d to week %d to replay the playoffs", leagueID, league.MaxWeeks)
+	}
+
+	matches, err := s.repo.GetMatchesByLeagueIdAndWeek(leagueID, week)
+	if err != nil {
+		return 0, err
+	}
+	stats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(leagueID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get team stats for league %d: %w", leagueID, err)
+	}
+	statsMap := make(map[uint]*models.TeamStats, len(stats))
+	for i := range stats {
+		statsMap[stats[i].TeamID] = &stats[i]
+	}
+	points := utils.NewRules(*league).Points
+	for _, match := range matches {
+		homeStats, awayStats := statsMap[match.HomeTeamID], statsMap[match.AwayTeamID]
+		if !match.Played || homeStats == nil || awayStats == nil {
+			continue
+		}
+		utils.RevertMatchResult(homeStats, awayStats, match, points)
+	}
+
+	// The weekly log of the previous week holds the estimations published after it
+	for i := range stats {
+		stats[i].Estimation, stats[i].EstimationIterations, stats[i].EstimationStdErr = 0, 0, 0
+	}
+	if week > 1 {
+		previous, err := s.weekSnapshot(league, week-1)
+		if err != nil {
+			return 0, err
+		}
+		for _, stat := range previous {
+			if current := statsMap[stat.TeamID]; current != nil {
+				current.Estimation = stat.Estimation
+				current.EstimationIterations = stat.EstimationIterations
+				current.EstimationStdErr = stat.EstimationStdErr
+			}
+		}
+	}
+
+	if err := s.repo.RewindLeague(*league, week-1, stats, repository.AuditUndoWeek, nil); err != nil {
+		return 0, fmt.Errorf("failed to undo week %d of league %d: %w", week, leagueID, err)
+	}
+	return week, nil
+}
+
+// EditMatchResult corrects the result of a played league match. The team stats are rebuilt from
+// every played match and the ratings replayed from the start of the season; the weekly logs of the
+// weeks from the week of the match on are computed again. Only the estimations after the last week
+// played are published before returning, the earlier weeks are estimated again in the background.
+// Goal events no longer matching the score are dropped, cards are kept. The rounds a Swiss-system
+// league already paired keep their pairings.
+func (s *LeagueService) EditMatchResult(matchID uint, req dto.MatchResultRequest) (*models.Match, error) {
+	var match *models.Match
+	if err := s.inTransaction(func(tx *LeagueService) error {
+		var err error
+		match, err = tx.editMatchResult(matchID, req)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	s.republishEstimations(match.LeagueID)
+	go s.estimateHistory(match.LeagueID, match.Week)
+	return match, nil
+}
+
+// editMatchResult changes the result of the match with the league locked, so the history is
+// recomputed from the league as it is committed
+func (s *LeagueService) editMatchResult(matchID uint, req dto.MatchResultRequest) (*models.Match, error) {
+	match, err := s.matchService.GetMatchByID(matchID)
+	if err != nil {
+		return nil, err
+	}
+	league, err := s.repo.LockLeague(match.LeagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock league %d: %w", match.LeagueID, err)
+	}
+	if !match.Played {
+		return nil, fmt.Errorf("match %d has not been played yet", matchID)
+	}
+	if match.TieID != nil {
+		return nil, fmt.Errorf("match %d is a leg of a knockout tie, its result cannot be changed", matchID)
+	}
+	if err := s.requireRewritableHistory(league); err != nil {
+		return nil, err
+	}
+	if utils.HasPlayoffs(league.Playoffs) && league.CurrWeek > league.MaxWeeks {
+		return nil, fmt.Errorf("the playoffs of league %d are drawn, rewind it to week %d to change a result", league.ID, league.MaxWeeks-1)
+	}
+	if req.HomeScore < 0 || req.AwayScore < 0 {
+		return nil, fmt.Errorf("scores cannot be negative")
+	}
+	if err := helpers.ValidatePenalties(dto.UserPlayedMatch{
+		MatchID:       matchID,
+		HomeScore:     req.HomeScore,
+		AwayScore:     req.AwayScore,
+		HomePenalties: req.HomePenalties,
+		AwayPenalties: req.AwayPenalties,
+	}, league.Points.NoDraws); err != nil {
+		return nil, err
+	}
+
+	match.HomeScore = req.HomeScore
+	match.AwayScore = req.AwayScore
+	match.HomePenalties = req.HomePenalties
+	match.AwayPenalties = req.AwayPenalties
+	match.Result = utils.MatchWinner(*match)
+	var events []models.MatchEvent
+	for _, event := range match.Events {
+		if event.Type != utils.EventGoal {
+			events = append(events, event)
+		}
+	}
+	match.Events = events
+
+	rewrite, err := s.rewriteHistory(league, *match)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RewriteResult(*league, *rewrite); err != nil {
+		return nil, fmt.Errorf("failed to change the result of match %d: %w", matchID, err)
+	}
+	return match, nil
+}
+
+// seasonHistory holds what the weeks of a league are recomputed from
+type seasonHistory struct {
+	matches        []models.Match
+	teams          []models.Team
+	teamIDs        []uint
+	initialRatings map[uint]float64
+}
+
+// loadHistory returns the matches and teams of the league with the ratings the season started from
+func (s *LeagueService) loadHistory(league *models.League) (*seasonHistory, error) {
+	matches, err := s.matchService.GetMatchesByLeagueId(league.ID)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.repo.GetTeamsByLeagueID(league.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams for league %d: %w", league.ID, err)
+	}
+	teamIDs := make([]uint, len(teams))
+	for i, team := range teams {
+		teamIDs[i] = team.ID
+	}
+	initialRatings, err := s.teamRatingRepo.GetInitialRatings(league.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &seasonHistory{matches: matches, teams: teams, teamIDs: teamIDs, initialRatings: initialRatings}, nil
+}
+
+// rewriteHistory recomputes the history of the league with the changed match from the match week
+// on. The weekly stats carry no estimations, they are published once the change is committed.
+func (s *LeagueService) rewriteHistory(league *models.League, changed models.Match) (*repository.ResultRewrite, error) {
+	history, err := s.loadHistory(league)
+	if err != nil {
+		return nil, err
+	}
+	for i := range history.matches {
+		if history.matches[i].ID == changed.ID {
+			history.matches[i] = changed
+		}
+	}
+	points := utils.NewRules(*league).Points
+
+	rewrite := &repository.ResultRewrite{Match: changed}
+	rewrite.Ratings, rewrite.TeamRatings = utils.ReplayRatings(history.initialRatings, history.matches)
+	lastWeek := min(league.CurrWeek-1, league.MaxWeeks)
+	for week := changed.Week; week <= lastWeek; week++ {
+		stats := utils.ProjectStandings(history.teamIDs, history.matches, week, points)
+		rewrite.WeeklyStats = append(rewrite.WeeklyStats, stats)
+		rewrite.Stats = stats
+	}
+	return rewrite, nil
+}
+
+// estimateHistory estimates again the weeks of the league from the given week up to the one before
+// the last week played, whose estimations are published with the change, and stores them in their
+// weekly logs and projections. Each week is estimated outside any transaction and stored unless
+// another request changed the league meanwhile, which stops the estimation.
+func (s *LeagueService) estimateHistory(leagueID uint, from int) {
+	league, err := s.repo.GetLeagueByID(leagueID)
+	if err != nil {
+		fmt.Printf("failed to get league by ID %d: %v\n", leagueID, err)
+		return
+	}
+	history, err := s.loadHistory(league)
+	if err != nil {
+		fmt.Printf("failed to load the history of league %d: %v\n", leagueID, err)
+		return
+	}
+	engine, err := utils.NewMatchEngine(*league)
+	if err != nil {
+		fmt.Printf("failed to create the match engine of league %d: %v\n", leagueID, err)
+		return
+	}
+	rules := utils.NewRules(*league)
+	ratings, _ := utils.ReplayRatings(history.initialRatings, history.matches)
+
+	lastWeek := min(league.CurrWeek-2, league.MaxWeeks)
+	for week := from; week <= lastWeek; week++ {
+		if !estimationsAvailable(league, week) {
+			continue
+		}
+		stats := utils.ProjectStandings(history.teamIDs, history.matches, week, rules.Points)
+		state := leagueStateAt(league, week, history.teams, stats, history.matches, history.initialRatings, ratings)
+		projection, err := utils.EstimateChampionshipProbabilities(state, engine, rules, utils.NewSimulationOptions(*league, week))
+		if err != nil {
+			fmt.Printf("failed to estimate week %d of league %d: %v\n", week, leagueID, err)
+			return
+		}
+		for i, estimation := range projection.Estimations {
+			stats[i].Estimation = estimation.Estimation
+			stats[i].EstimationIterations = estimation.Iterations
+			stats[i].EstimationStdErr = estimation.StdErr
+		}
+
+		stored := false
+		if err := s.inTransaction(func(tx *LeagueService) error {
+			current, err := tx.repo.LockLeague(leagueID)
+			if err != nil {
+				return err
+			}
+			if current.Version != league.Version {
+				return nil
+			}
+			if err := tx.weeklyLogRepo.UpdateWeeklyLog(leagueID, week, stats); err != nil {
+				return err
+			}
+			if err := tx.projectionRepo.SaveProjection(*projection); err != nil {
+				return fmt.Errorf("failed to save projection: %w", err)
+			}
+			stored = true
+			return nil
+		}); err != nil {
+			fmt.Printf("failed to store the estimations of week %d of league %d: %v\n", week, leagueID, err)
+			return
+		}
+		if !stored {
+			fmt.Printf("League %d changed while estimating week %d, estimations dropped\n", leagueID, week)
+			return
+		}
+	}
+}
+
+// leagueStateAt returns the state of the league at the end of the given week of its regular season
+// from the stats of that week and the matches of the league, the teams rated as they were then
+func leagueStateAt(league *models.League, week int, teams []models.Team, stats []models.TeamStats, matches []models.Match, initialRatings map[uint]float64, ratings []models.TeamRating) dto.LeagueState {
+	ratingAt := make(map[uint]float64, len(initialRatings))
+	for teamID, rating := range initialRatings {
+		ratingAt[teamID] = rating
+	}
+	for _, rating := range ratings {
+		if rating.Week <= week {
+			ratingAt[rating.TeamID] = rating.Rating
+		}
+	}
+	ratedTeams := make([]models.Team, len(teams))
+	for i, team := range teams {
+		ratedTeams[i] = team
+		ratedTeams[i].Rating = ratingAt[team.ID]
+	}
+
+	state := dto.LeagueState{
+		LeagueID:     league.ID,
+		Week:         week,
+		Teams:        ratedTeams,
+		TeamStats:    stats,
+		PlayoffRound: utils.PlayoffRound(*league, week+1),
+	}
+	for _, match := range matches {
+		switch {
+		case match.TieID != nil:
+		case match.Week <= week:
+			if match.Played {
+				state.PlayedMatches = append(state.PlayedMatches, match)
+			}
+		// A Swiss-system league had only paired the round following the week
+		case league.Format != utils.FormatSwiss || match.Week == week+1:
+			state.RemainingMatches = append(state.RemainingMatches, models.Match{
+				ID:         match.ID,
+				LeagueID:   match.LeagueID,
+				Week:       match.Week,
+				HomeTeamID: match.HomeTeamID,
+				AwayTeamID: match.AwayTeamID,
+				Seed:       match.Seed,
+			})
+		}
+	}
+	return state
+}
+
+// requireRewritableHistory fails for competitions whose history cannot be changed on its own: cups,
+// groups of a tournament, divisions of a pyramid and seasons followed by a later season
+func (s *LeagueService) requireRewritableHistory(league *models.League) error {
+	if err := requireLeagueFormat(league); err != nil {
+		return err
+	}
+	if league.TournamentID != nil || league.PyramidID != nil {
+		return fmt.Errorf("league %d is part of a tournament or pyramid, its history cannot be changed on its own", league.ID)
+	}
+	seasons, err := s.repo.GetCompetitionSeasons(utils.CompetitionRoot(*league))
+	if err != nil {
+		return err
+	}
+	for _, season := range seasons {
+		if season.Season > league.Season {
+			return fmt.Errorf("season %d of league %d is followed by season %d, its history cannot be changed", league.Season, league.ID, season.Season)
+		}
+	}
+	return nil
+}
+
+// weekSnapshot returns the team stats of the league at the end of the given week, 0 for the start of the season
+func (s *LeagueService) weekSnapshot(league *models.League, week int) ([]models.TeamStats, error) {
+	teams, err := s.repo.GetTeamsByLeagueID(league.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get teams for league %d: %w", league.ID, err)
+	}
+	if week == 0 {
+		stats := make([]models.TeamStats, len(teams))
+		for i, team := range teams {
+			stats[i] = models.TeamStats{TeamID: team.ID}
+		}
+		return stats, nil
+	}
+
+	log, err := s.weeklyLogRepo.GetWeeklyLogByLeagueIDAndWeek(league.ID, week)
+	if err != nil {
+		return nil, err
+	}
+	var stats []models.TeamStats
+	if err := json.Unmarshal([]byte(log.TeamStatsJSON), &stats); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal team stats of week %d: %w", week, err)
+	}
+	if len(stats) != len(teams) {
+		return nil, fmt.Errorf("weekly log of week %d has stats of %d teams, league %d has %d", week, len(stats), league.ID, len(teams))
+	}
+	return stats, nil
+}
+
+// GetAuditTrail returns the changes rewriting the history of the league, oldest first
+func (s *LeagueService) GetAuditTrail(leagueID uint) ([]models.LeagueAudit, error) {
+	if _, err := s.repo.GetLeagueByID(leagueID); err != nil {
+		return nil, fmt.Errorf("failed to get league by ID %d: %w", leagueID, err)
+	}
+	return s.auditRepo.GetAuditTrail(leagueID)
+}
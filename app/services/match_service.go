@@ -3,43 +3,58 @@ package services
 import (
 	"fmt"
 	"insider-case/app/dto"
+	"insider-case/app/helpers"
 	"insider-case/app/models"
 	"insider-case/app/repository"
 
 	"insider-case/app/utils"
-	"math/rand"
-	"time"
 )
 
-const homeAdvantageMultiplier = 1.1 // 10% advantage to home team
-
 type IMatchService interface {
 	GetMatchesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Match, error)
 	GetMatchesByLeagueId(leagueID uint) ([]models.Match, error)
+	GetByesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Bye, error)
+	GetMatchByID(matchID uint) (*models.Match, error)
 	// PlayMatch(match models.Match) error
-	SimulateMatch(match models.Match) (models.Match, error)
-	UserPlayMatch(week dto.UserPlayedMatch) (models.Match, error)
+	SimulateMatch(match models.Match, engine utils.MatchEngine, rules utils.Rules) (models.Match, error)
+	UserPlayMatch(week dto.UserPlayedMatch, rules utils.Rules) (models.Match, error)
+	RecordTieLeg(match models.Match, result utils.MatchResult) (models.Match, error)
+	ScheduleSwissRound(league models.League, week int, ranked []uint) ([]models.Match, []models.Bye, error)
+	WithRepositories(repos repository.Repositories) IMatchService
 }
 
 type MatchService struct {
-	matchRepo     repository.IMatchRepository
-	teamRepo      repository.ITeamRepository
-	teamStatsRepo repository.ITeamStatsRepository
+	matchRepo      repository.IMatchRepository
+	teamRepo       repository.ITeamRepository
+	teamStatsRepo  repository.ITeamStatsRepository
+	teamRatingRepo repository.ITeamRatingRepository
 }
 
 var _ IMatchService = &MatchService{}
 
-func NewMatchService(matchRepo repository.IMatchRepository, teamRepo repository.ITeamRepository, teamStatsRepo repository.ITeamStatsRepository) *MatchService {
-	if matchRepo == nil || teamRepo == nil || teamStatsRepo == nil {
+func NewMatchService(matchRepo repository.IMatchRepository, teamRepo repository.ITeamRepository, teamStatsRepo repository.ITeamStatsRepository, teamRatingRepo repository.ITeamRatingRepository) *MatchService {
+	if matchRepo == nil || teamRepo == nil || teamStatsRepo == nil || teamRatingRepo == nil {
 		fmt.Println("repositories not initialized")
 		return nil
 	}
 	return &MatchService{
-		matchRepo:     matchRepo,
-		teamRepo:      teamRepo,
-		teamStatsRepo: teamStatsRepo,
+		matchRepo:      matchRepo,
+		teamRepo:       teamRepo,
+		teamStatsRepo:  teamStatsRepo,
+		teamRatingRepo: teamRatingRepo,
 	}
 }
+
+// WithRepositories returns a copy of the service working through the repositories of a unit of work
+func (s *MatchService) WithRepositories(repos repository.Repositories) IMatchService {
+	return &MatchService{
+		matchRepo:      repos.Matches,
+		teamRepo:       repos.Teams,
+		teamStatsRepo:  repos.TeamStats,
+		teamRatingRepo: repos.TeamRatings,
+	}
+}
+
 func (s *MatchService) GetMatchesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Match, error) {
 
 	fmt.Println("Fetching matches for league:", leagueID, "week:", week)
@@ -66,7 +81,28 @@ func (s *MatchService) GetMatchesByLeagueId(leagueID uint) ([]models.Match, erro
 	return matches, nil
 }
 
-func (s *MatchService) updateTeamStats(match models.Match) error {
+func (s *MatchService) GetByesByLeagueIdAndWeek(leagueID uint, week int) ([]models.Bye, error) {
+	byes, err := s.matchRepo.GetByesByLeagueIdAndWeek(leagueID, week)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get byes for league %d and week %d: %w", leagueID, week, err)
+	}
+	return byes, nil
+}
+
+func (s *MatchService) GetMatchByID(matchID uint) (*models.Match, error) {
+	return s.matchRepo.GetMatchByID(matchID)
+}
+
+// ScheduleSwissRound pairs the given week of a Swiss-system league from the team IDs ordered by the standings
+func (s *MatchService) ScheduleSwissRound(league models.League, week int, ranked []uint) ([]models.Match, []models.Bye, error) {
+	matches, byes, err := s.matchRepo.ScheduleSwissRound(league, week, ranked)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to pair week %d of league %d: %w", week, league.ID, err)
+	}
+	return matches, byes, nil
+}
+
+func (s *MatchService) updateTeamStats(match models.Match, points models.PointsSystem) error {
 	homeTeamStats, err := s.teamStatsRepo.GetTeamStatsByTeamID(match.HomeTeamID)
 	if err != nil {
 		return fmt.Errorf("failed to get home team %d: %w", match.HomeTeamID, err)
@@ -77,43 +113,45 @@ func (s *MatchService) updateTeamStats(match models.Match) error {
 		return fmt.Errorf("failed to get away team %d: %w", match.AwayTeamID, err)
 	}
 
-	// Update stats logic here
-	// ...
-	homeTeamStats.Played++
-	awayTeamStats.Played++
+	utils.ApplyMatchResult(&homeTeamStats, &awayTeamStats, match, points)
 
-	if match.HomeScore > match.AwayScore {
-		homeTeamStats.Won++
-		awayTeamStats.Lost++
-		homeTeamStats.Points += 3
-
-	} else if match.HomeScore < match.AwayScore {
-		homeTeamStats.Lost++
-		awayTeamStats.Won++
-		awayTeamStats.Points += 3
-	} else {
-		homeTeamStats.Draw++
-		awayTeamStats.Draw++
-		homeTeamStats.Points += 1
-		awayTeamStats.Points += 1
+	if err := s.teamStatsRepo.UpdateTeamStats(homeTeamStats); err != nil {
+		return fmt.Errorf("failed to save stats of home team %d: %w", match.HomeTeamID, err)
+	}
+	if err := s.teamStatsRepo.UpdateTeamStats(awayTeamStats); err != nil {
+		return fmt.Errorf("failed to save stats of away team %d: %w", match.AwayTeamID, err)
 	}
 
-	homeTeamStats.GoalsFor += match.HomeScore
-	awayTeamStats.GoalsFor += match.AwayScore
-	homeTeamStats.GoalsAgainst += match.AwayScore
-	awayTeamStats.GoalsAgainst += match.HomeScore
-	homeTeamStats.GoalDiff += match.HomeScore - match.AwayScore
-	awayTeamStats.GoalDiff += match.AwayScore - match.HomeScore
+	return nil
+}
 
-	s.teamStatsRepo.UpdateTeamStats(homeTeamStats)
-	s.teamStatsRepo.UpdateTeamStats(awayTeamStats)
+// updateTeamRatings applies the Elo update of a played match and records the new ratings
+func (s *MatchService) updateTeamRatings(match models.Match, homeTeam, awayTeam models.Team) error {
+	homeRating, awayRating := utils.UpdateElo(homeTeam.Rating, awayTeam.Rating, match.HomeScore, match.AwayScore)
 
+	for _, team := range []struct {
+		id     uint
+		rating float64
+	}{{homeTeam.ID, homeRating}, {awayTeam.ID, awayRating}} {
+		if err := s.teamRepo.UpdateTeamRating(team.id, team.rating); err != nil {
+			return err
+		}
+		if err := s.teamRatingRepo.SaveTeamRating(models.TeamRating{
+			TeamID:   team.id,
+			LeagueID: match.LeagueID,
+			Week:     match.Week,
+			MatchID:  &match.ID,
+			Rating:   team.rating,
+		}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *MatchService) SimulateMatch(match models.Match) (models.Match, error) {
-	// Seed the RNG
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+func (s *MatchService) SimulateMatch(match models.Match, engine utils.MatchEngine, rules utils.Rules) (models.Match, error) {
+	// Seed the RNG with the match seed so the result can be reproduced
+	r := utils.NewRand(match.Seed)
 	fmt.Println("Simulating match:", match.ID, "between teams:", match.HomeTeamID, "and", match.AwayTeamID)
 
 	homeTeam, err := s.teamRepo.GetTeamByID(match.HomeTeamID)
@@ -124,56 +162,35 @@ func (s *MatchService) SimulateMatch(match models.Match) (models.Match, error) {
 	if err != nil {
 		return match, fmt.Errorf("failed to get away team %d: %w", match.AwayTeamID, err)
 	}
-	teams := append([]models.Team{}, homeTeam, awayTeam)
 	teamStats, err := s.teamStatsRepo.GetTeamStatsByLeagueID(match.LeagueID)
 	if err != nil {
 		return match, fmt.Errorf("failed to get team stats for league %d: %w", match.LeagueID, err)
 	}
-	formFactor := utils.CalculateFormFactor(teams, teamStats, match.HomeTeamID)
-	if formFactor <= 0 {
-		formFactor = 1.0 // Default form factor if calculation fails
-	}
-
-	// Total strength for probability calculations
-	totalStrength := (float64(homeTeam.Strength) * homeAdvantageMultiplier * formFactor) + float64(awayTeam.Strength)
-
-	// Compute win probabilities
-	homeWinChance := float64(homeTeam.Strength) / totalStrength * 0.8
-	drawChance := 0.2 // fixed draw chance
 
-	// Simulate match result
-	outcome := rand.Float64()
-
-	var homeGoals, awayGoals int
-	switch {
-	case outcome < homeWinChance:
-		homeGoals = r.Intn(3) + 1 // 1 to 3
-		awayGoals = r.Intn(3)     // 0 to 1
-	case outcome < homeWinChance+drawChance:
-		goals := r.Intn(3) // 0 to 2
-		homeGoals = goals
-		awayGoals = goals
-	default:
-		awayGoals = r.Intn(3) + 1 // 1 to 3
-		homeGoals = r.Intn(3)     // 0 to 1
-	}
-	s.setMatchWinner(&match)
+	result := utils.PlayMatch(engine, rules, homeTeam, awayTeam, teamStats, r)
 
-	match.HomeScore = homeGoals
-	match.AwayScore = awayGoals
+	match.HomeScore = result.HomeGoals
+	match.AwayScore = result.AwayGoals
+	match.HomePenalties = result.HomePenalties
+	match.AwayPenalties = result.AwayPenalties
+	match.Events = result.Events
 	match.Played = true
+	s.setMatchWinner(&match)
 
 	if err := s.matchRepo.SaveMatch(match); err != nil {
 		return match, fmt.Errorf("failed to save simulated match %d: %w", match.ID, err)
 	}
-	if err := s.updateTeamStats(match); err != nil {
+	if err := s.updateTeamStats(match, rules.Points); err != nil {
 		return match, fmt.Errorf("failed to update team stats for match %d: %w", match.ID, err)
 	}
+	if err := s.updateTeamRatings(match, homeTeam, awayTeam); err != nil {
+		return match, fmt.Errorf("failed to update team ratings for match %d: %w", match.ID, err)
+	}
 
 	return match, nil
 
 }
-func (s *MatchService) UserPlayMatch(match dto.UserPlayedMatch) (models.Match, error) {
+func (s *MatchService) UserPlayMatch(match dto.UserPlayedMatch, rules utils.Rules) (models.Match, error) {
 	existingMatch, err := s.matchRepo.GetMatchByID(match.MatchID)
 	if err != nil {
 		return models.Match{}, fmt.Errorf("failed to get match %d: %w", match.MatchID, err)
@@ -181,25 +198,64 @@ func (s *MatchService) UserPlayMatch(match dto.UserPlayedMatch) (models.Match, e
 	if existingMatch == nil {
 		return models.Match{}, fmt.Errorf("match with ID %d not found", match.MatchID)
 	}
+	if existingMatch.Played {
+		return models.Match{}, &helpers.ValidationError{Field: "match_id", Message: fmt.Sprintf("match %d is already played", match.MatchID)}
+	}
 	existingMatch.HomeScore = match.HomeScore
 	existingMatch.AwayScore = match.AwayScore
+	existingMatch.HomePenalties = match.HomePenalties
+	existingMatch.AwayPenalties = match.AwayPenalties
 	existingMatch.Played = true
 	s.setMatchWinner(existingMatch)
 	if err := s.matchRepo.SaveMatch(*existingMatch); err != nil {
 		return models.Match{}, fmt.Errorf("failed to save match %d: %w", match.MatchID, err)
 	}
-	if err := s.updateTeamStats(*existingMatch); err != nil {
+	if err := s.updateTeamStats(*existingMatch, rules.Points); err != nil {
 		return models.Match{}, fmt.Errorf("failed to update team stats for match %d: %w", match.MatchID, err)
 	}
+	homeTeam, err := s.teamRepo.GetTeamByID(existingMatch.HomeTeamID)
+	if err != nil {
+		return models.Match{}, fmt.Errorf("failed to get home team %d: %w", existingMatch.HomeTeamID, err)
+	}
+	awayTeam, err := s.teamRepo.GetTeamByID(existingMatch.AwayTeamID)
+	if err != nil {
+		return models.Match{}, fmt.Errorf("failed to get away team %d: %w", existingMatch.AwayTeamID, err)
+	}
+	if err := s.updateTeamRatings(*existingMatch, homeTeam, awayTeam); err != nil {
+		return models.Match{}, fmt.Errorf("failed to update team ratings for match %d: %w", match.MatchID, err)
+	}
 	return *existingMatch, nil
 
 }
-func (s *MatchService) setMatchWinner(match *models.Match) {
-	if match.HomeScore > match.AwayScore {
-		match.Result = &match.HomeTeamID
-	} else if match.AwayScore > match.HomeScore {
-		match.Result = &match.AwayTeamID
-	} else {
-		match.Result = nil // Draw
+
+// RecordTieLeg saves a leg of a knockout tie. Knockout matches update the ratings but not the league table.
+func (s *MatchService) RecordTieLeg(match models.Match, result utils.MatchResult) (models.Match, error) {
+	match.HomeScore = result.HomeGoals
+	match.AwayScore = result.AwayGoals
+	match.HomePenalties = result.HomePenalties
+	match.AwayPenalties = result.AwayPenalties
+	match.ExtraTime = result.ExtraTime
+	match.Events = result.Events
+	match.Played = true
+	s.setMatchWinner(&match)
+
+	if err := s.matchRepo.SaveMatch(match); err != nil {
+		return match, fmt.Errorf("failed to save tie leg %d: %w", match.ID, err)
 	}
+	homeTeam, err := s.teamRepo.GetTeamByID(match.HomeTeamID)
+	if err != nil {
+		return match, fmt.Errorf("failed to get home team %d: %w", match.HomeTeamID, err)
+	}
+	awayTeam, err := s.teamRepo.GetTeamByID(match.AwayTeamID)
+	if err != nil {
+		return match, fmt.Errorf("failed to get away team %d: %w", match.AwayTeamID, err)
+	}
+	if err := s.updateTeamRatings(match, homeTeam, awayTeam); err != nil {
+		return match, fmt.Errorf("failed to update team ratings for match %d: %w", match.ID, err)
+	}
+	return match, nil
+}
+
+func (s *MatchService) setMatchWinner(match *models.Match) {
+	match.Result = utils.MatchWinner(*match) // nil for a draw
 }
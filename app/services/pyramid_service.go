@@ -0,0 +1,358 @@
+package services
+
+import (
+	"fmt"
+	"insider-case/app/dto"
+	"insider-case/app/helpers"
+	"insider-case/app/models"
+	"insider-case/app/repository"
+	"insider-case/app/utils"
+)
+
+type IPyramidService interface {
+	CreatePyramid(req dto.PyramidCreateRequest) (*dto.PyramidResponse, error)
+	GetPyramid(pyramidID uint) (*dto.PyramidResponse, error)
+	SimulateWeek(pyramidID uint) (*dto.PyramidResponse, error)
+	SimulateSeason(pyramidID uint) (*dto.PyramidResponse, error)
+	GetProbabilities(pyramidID uint, req dto.EstimationRequest) (*dto.PyramidProjection, error)
+	NextSeason(pyramidID uint) (*dto.PyramidResponse, error)
+}
+
+type PyramidService struct {
+	repo          repository.IPyramidRepository
+	leagueRepo    repository.ILeagueRepository
+	leagueService ILeagueService
+	uow           repository.IUnitOfWork
+}
+
+var _ IPyramidService = &PyramidService{}
+
+func NewPyramidService(repo repository.IPyramidRepository, leagueRepo repository.ILeagueRepository, leagueService ILeagueService, uow repository.IUnitOfWork) *PyramidService {
+	return &PyramidService{
+		repo:          repo,
+		leagueRepo:    leagueRepo,
+		leagueService: leagueService,
+		uow:           uow,
+	}
+}
+
+// withRepositories returns a copy of the service working through the repositories of a unit of work
+func (s *PyramidService) withRepositories(repos repository.Repositories) *PyramidService {
+	return &PyramidService{
+		repo:          repos.Pyramids,
+		leagueRepo:    repos.Leagues,
+		leagueService: s.leagueService.WithRepositories(repos),
+		uow:           repos.UnitOfWork,
+	}
+}
+
+func (s *PyramidService) CreatePyramid(req dto.PyramidCreateRequest) (*dto.PyramidResponse, error) {
+	swaps := utils.DefaultSwaps
+	if req.Swaps != nil {
+		swaps = *req.Swaps
+	}
+	if err := helpers.ValidatePyramid(req.Divisions, swaps); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateEngine(req.Engine); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateEngineParams(req.EngineParams); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateSimulationSettings(req.SimulationIterations, req.TargetStdErr); err != nil {
+		return nil, err
+	}
+	if req.SimulationIterations == 0 {
+		req.SimulationIterations = utils.DefaultSimulationIterations
+	}
+	if err := helpers.ValidateLegs(req.Legs); err != nil {
+		return nil, err
+	}
+	legs := utils.DefaultLegs
+	if req.Legs != nil {
+		legs = *req.Legs
+	}
+	if err := helpers.ValidateTieBreakers(req.TieBreakers); err != nil {
+		return nil, err
+	}
+	if len(req.TieBreakers) == 0 {
+		req.TieBreakers = utils.DefaultTieBreakers
+	}
+	points := pointsSystemFromRequest(req.Points)
+	if err := helpers.ValidatePointsSystem(points); err != nil {
+		return nil, err
+	}
+	if req.Engine == "" {
+		req.Engine = utils.DefaultEngine
+	}
+	seed := utils.GenerateSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	pyramid := &models.Pyramid{
+		Name:   req.Name,
+		Swaps:  swaps,
+		Season: utils.FirstSeason,
+		Seed:   seed,
+	}
+
+	divisions := make([]*models.League, len(req.Divisions))
+	for d, division := range req.Divisions {
+		name := division.Name
+		if name == "" {
+			name = fmt.Sprintf("%s Division %d", req.Name, d+1)
+		}
+		promoted, relegated := utils.DivisionMoves(d+1, len(req.Divisions), swaps)
+		divisions[d] = &models.League{
+			Name:         name,
+			Format:       utils.FormatLeague,
+			TeamCount:    len(division.Teams),
+			MaxWeeks:     helpers.CalculateMaxWeeks(len(division.Teams), legs),
+			Legs:         legs,
+			Engine:       req.Engine,
+			EngineParams: engineParamsFromRequest(req.EngineParams),
+			UseRating:    req.UseRating,
+			Seed:         utils.DivisionSeed(*pyramid, pyramid.Season, d+1),
+
+			SimulationIterations: req.SimulationIterations,
+			TargetStdErr:         req.TargetStdErr,
+
+			Season:    pyramid.Season,
+			Division:  d + 1,
+			Promoted:  promoted,
+			Relegated: relegated,
+
+			TieBreakers: req.TieBreakers,
+			Points:      points,
+
+			Teams: make([]models.Team, len(division.Teams)),
+		}
+		for i, team := range division.Teams {
+			divisions[d].Teams[i] = models.Team{
+				Name:     team.Name,
+				Strength: team.Strength,
+			}
+		}
+	}
+
+	createdPyramid, err := s.repo.InitializePyramid(pyramid, divisions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize pyramid: %w", err)
+	}
+	return s.pyramidResponse(createdPyramid)
+}
+
+func (s *PyramidService) GetPyramid(pyramidID uint) (*dto.PyramidResponse, error) {
+	pyramid, err := s.getPyramid(pyramidID)
+	if err != nil {
+		return nil, err
+	}
+	return s.pyramidResponse(pyramid)
+}
+
+// SimulateWeek plays the next week of every division still playing its season. The divisions are
+// played in a single unit of work with the pyramid locked, so they stay in step: a failure in one
+// division leaves every division as it was. Their estimations are published once it is committed.
+func (s *PyramidService) SimulateWeek(pyramidID uint) (*dto.PyramidResponse, error) {
+	var divisionIDs []uint
+	err := s.uow.Do(func(repos repository.Repositories) error {
+		var err error
+		divisionIDs, err = s.withRepositories(repos).simulateWeek(pyramidID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, divisionID := range divisionIDs {
+		if err := s.leagueService.PublishEstimations(divisionID); err != nil {
+			fmt.Printf("failed to publish estimations of league %d: %v\n", divisionID, err)
+		}
+	}
+	return s.GetPyramid(pyramidID)
+}
+
+// simulateWeek plays the next week of the pyramid and returns the divisions that played it
+func (s *PyramidService) simulateWeek(pyramidID uint) ([]uint, error) {
+	pyramid, err := s.repo.LockPyramid(pyramidID)
+	if err != nil {
+		return nil, err
+	}
+	if seasonFinished(pyramid) {
+		return nil, fmt.Errorf("season %d of pyramid %d is finished, start the next season", pyramid.Season, pyramidID)
+	}
+	var divisionIDs []uint
+	for _, division := range pyramid.Divisions {
+		if utils.LeagueFinished(division) {
+			continue
+		}
+		if _, err := s.leagueService.PlayWeek(division.ID); err != nil {
+			return nil, fmt.Errorf("failed to simulate %s: %w", division.Name, err)
+		}
+		divisionIDs = append(divisionIDs, division.ID)
+	}
+	return divisionIDs, nil
+}
+
+// SimulateSeason plays the rest of the season of every division, a pyramid week at a time
+func (s *PyramidService) SimulateSeason(pyramidID uint) (*dto.PyramidResponse, error) {
+	for {
+		pyramid, err := s.getPyramid(pyramidID)
+		if err != nil {
+			return nil, err
+		}
+		if seasonFinished(pyramid) {
+			return s.pyramidResponse(pyramid)
+		}
+		if _, err := s.SimulateWeek(pyramidID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// GetProbabilities estimates the rest of the season of every division, including the probability
+// of every team being promoted or relegated
+func (s *PyramidService) GetProbabilities(pyramidID uint, req dto.EstimationRequest) (*dto.PyramidProjection, error) {
+	pyramid, err := s.getPyramid(pyramidID)
+	if err != nil {
+		return nil, err
+	}
+	projection := &dto.PyramidProjection{
+		PyramidID: pyramid.ID,
+		Season:    pyramid.Season,
+		Divisions: make([]dto.LeagueProjection, len(pyramid.Divisions)),
+	}
+	for d, division := range pyramid.Divisions {
+		divisionProjection, err := s.leagueService.EstimateProjection(division.ID, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate %s: %w", division.Name, err)
+		}
+		projection.Divisions[d] = *divisionProjection
+	}
+	return projection, nil
+}
+
+// NextSeason starts the next season once every division is played. The promoted teams of every
+// division move up, its relegated teams move down and the clubs keep their strength and rating.
+func (s *PyramidService) NextSeason(pyramidID uint) (*dto.PyramidResponse, error) {
+	pyramid, err := s.getPyramid(pyramidID)
+	if err != nil {
+		return nil, err
+	}
+	if !seasonFinished(pyramid) {
+		return nil, fmt.Errorf("season %d of pyramid %d is still being played", pyramid.Season, pyramidID)
+	}
+
+	ranked := make([][]models.TeamStats, len(pyramid.Divisions))
+	teams := make(map[uint]models.Team)
+	for d, division := range pyramid.Divisions {
+		standings, err := s.leagueService.GetStandings(division.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rank %s: %w", division.Name, err)
+		}
+		ranked[d] = make([]models.TeamStats, len(standings.Table))
+		for i, entry := range standings.Table {
+			ranked[d][i] = entry.TeamStats
+		}
+		divisionTeams, err := s.leagueRepo.GetTeamsByLeagueID(division.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get teams of %s: %w", division.Name, err)
+		}
+		for _, team := range divisionTeams {
+			teams[team.ID] = team
+		}
+	}
+
+	season := pyramid.Season + 1
+	next := utils.RolloverDivisions(ranked, pyramid.Swaps)
+	divisions := make([]*models.League, len(next))
+	for d, teamIDs := range next {
+		previous := pyramid.Divisions[d]
+		divisions[d] = &models.League{
+			Name:         previous.Name,
+			Format:       utils.FormatLeague,
+			TeamCount:    len(teamIDs),
+			MaxWeeks:     helpers.CalculateMaxWeeks(len(teamIDs), previous.Legs),
+			Legs:         previous.Legs,
+			Engine:       previous.Engine,
+			EngineParams: previous.EngineParams,
+			UseRating:    previous.UseRating,
+			Seed:         utils.DivisionSeed(*pyramid, season, d+1),
+
+			SimulationIterations: previous.SimulationIterations,
+			TargetStdErr:         previous.TargetStdErr,
+
+			Season:    season,
+			Division:  previous.Division,
+			Promoted:  previous.Promoted,
+			Relegated: previous.Relegated,
+
+			TieBreakers: previous.TieBreakers,
+			Points:      previous.Points,
+
+			Teams: make([]models.Team, len(teamIDs)),
+		}
+		for i, teamID := range teamIDs {
+			divisions[d].Teams[i] = models.Team{
+				ClubID:   teams[teamID].ClubID,
+				Name:     teams[teamID].Name,
+				Strength: teams[teamID].Strength,
+				Rating:   teams[teamID].Rating,
+			}
+		}
+	}
+
+	updatedPyramid, err := s.repo.InitializeSeason(pyramid, divisions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start season %d of pyramid %d: %w", season, pyramidID, err)
+	}
+	return s.pyramidResponse(updatedPyramid)
+}
+
+func (s *PyramidService) getPyramid(pyramidID uint) (*models.Pyramid, error) {
+	pyramid, err := s.repo.GetPyramidByID(pyramidID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pyramid with ID %d: %w", pyramidID, err)
+	}
+	return pyramid, nil
+}
+
+// seasonFinished reports whether every division of the pyramid's current season is played
+func seasonFinished(pyramid *models.Pyramid) bool {
+	for _, division := range pyramid.Divisions {
+		if !utils.LeagueFinished(division) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *PyramidService) pyramidResponse(pyramid *models.Pyramid) (*dto.PyramidResponse, error) {
+	response := &dto.PyramidResponse{
+		ID:        pyramid.ID,
+		Name:      pyramid.Name,
+		Season:    pyramid.Season,
+		Swaps:     pyramid.Swaps,
+		Seed:      pyramid.Seed,
+		Finished:  seasonFinished(pyramid),
+		Divisions: make([]dto.PyramidDivision, len(pyramid.Divisions)),
+	}
+	for d, division := range pyramid.Divisions {
+		standings, err := s.leagueService.GetStandings(division.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rank %s: %w", division.Name, err)
+		}
+		response.Divisions[d] = dto.PyramidDivision{
+			LeagueID:  division.ID,
+			Name:      division.Name,
+			Division:  division.Division,
+			CurrWeek:  division.CurrWeek,
+			MaxWeeks:  division.MaxWeeks,
+			Promoted:  division.Promoted,
+			Relegated: division.Relegated,
+			Standings: standings.Table,
+		}
+	}
+	return response, nil
+}
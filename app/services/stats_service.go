@@ -0,0 +1,148 @@
+package services
+
+import (
+	"fmt"
+	"insider-case/app/dto"
+	"insider-case/app/models"
+	"insider-case/app/repository"
+	"insider-case/app/utils"
+)
+
+// IStatsService keeps the team stats, a cache updated match by match, in line with the matches
+type IStatsService interface {
+	RebuildStats(leagueID uint) (*dto.StatsReport, error)
+	CheckStats() ([]dto.StatsReport, error)
+}
+
+type StatsService struct {
+	leagueRepo    repository.ILeagueRepository
+	matchRepo     repository.IMatchRepository
+	teamStatsRepo repository.ITeamStatsRepository
+	uow           repository.IUnitOfWork
+}
+
+var _ IStatsService = &StatsService{}
+
+func NewStatsService(leagueRepo repository.ILeagueRepository, matchRepo repository.IMatchRepository, teamStatsRepo repository.ITeamStatsRepository, uow repository.IUnitOfWork) *StatsService {
+	if leagueRepo == nil || matchRepo == nil || teamStatsRepo == nil || uow == nil {
+		fmt.Println("repositories not initialized")
+		return nil
+	}
+	return &StatsService{
+		leagueRepo:    leagueRepo,
+		matchRepo:     matchRepo,
+		teamStatsRepo: teamStatsRepo,
+		uow:           uow,
+	}
+}
+
+// withRepositories returns a copy of the service working through the repositories of a unit of work
+func (s *StatsService) withRepositories(repos repository.Repositories) *StatsService {
+	return &StatsService{
+		leagueRepo:    repos.Leagues,
+		matchRepo:     repos.Matches,
+		teamStatsRepo: repos.TeamStats,
+		uow:           repos.UnitOfWork,
+	}
+}
+
+// RebuildStats replaces the stored team stats of the league with the stats derived from its played
+// matches. The championship estimations are kept. The league is locked while its matches are read
+// and the rebuild bumps its version and is recorded in the audit trail, like the other rewrites of
+// its history. The report lists the stats that were corrected.
+func (s *StatsService) RebuildStats(leagueID uint) (*dto.StatsReport, error) {
+	var report *dto.StatsReport
+	err := s.uow.Do(func(repos repository.Repositories) error {
+		var err error
+		report, err = s.withRepositories(repos).rebuildStats(leagueID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *StatsService) rebuildStats(leagueID uint) (*dto.StatsReport, error) {
+	league, err := s.leagueRepo.LockLeague(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	report, projected, err := s.compareStats(league)
+	if err != nil {
+		return nil, err
+	}
+	if report.Consistent {
+		return report, nil
+	}
+	if err := s.leagueRepo.RebuildStats(*league, projected); err != nil {
+		return nil, fmt.Errorf("failed to rebuild stats of league %d: %w", leagueID, err)
+	}
+	return report, nil
+}
+
+// CheckStats returns the leagues whose stored team stats disagree with their matches
+func (s *StatsService) CheckStats() ([]dto.StatsReport, error) {
+	leagues, err := s.leagueRepo.GetLeagues()
+	if err != nil {
+		return nil, err
+	}
+	reports := []dto.StatsReport{}
+	for i := range leagues {
+		report, _, err := s.compareStats(&leagues[i])
+		if err != nil {
+			return nil, err
+		}
+		if !report.Consistent {
+			fmt.Printf("Team stats of league %d disagree with its matches for %d teams\n", leagues[i].ID, len(report.Discrepancies))
+			reports = append(reports, *report)
+		}
+	}
+	return reports, nil
+}
+
+// compareStats projects the team stats of the league from its matches and compares them with the
+// stored ones. The projected stats carry the stored estimations.
+func (s *StatsService) compareStats(league *models.League) (*dto.StatsReport, []models.TeamStats, error) {
+	teams, err := s.leagueRepo.GetTeamsByLeagueID(league.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get teams for league %d: %w", league.ID, err)
+	}
+	matches, err := s.matchRepo.GetMatchesByLeagueId(league.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	stored, err := s.teamStatsRepo.GetTeamStatsByLeagueID(league.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get team stats for league %d: %w", league.ID, err)
+	}
+	storedByTeam := make(map[uint]models.TeamStats, len(stored))
+	for _, stats := range stored {
+		storedByTeam[stats.TeamID] = stats
+	}
+
+	teamIDs := make([]uint, len(teams))
+	for i, team := range teams {
+		teamIDs[i] = team.ID
+	}
+	// Only the matches of the regular season count towards the table
+	projected := utils.ProjectStandings(teamIDs, matches, league.MaxWeeks, utils.NewRules(*league).Points)
+
+	report := &dto.StatsReport{LeagueID: league.ID, LeagueName: league.Name, Consistent: true}
+	for i := range projected {
+		current, found := storedByTeam[projected[i].TeamID]
+		current.TeamID = projected[i].TeamID
+		projected[i].Estimation = current.Estimation
+		projected[i].EstimationIterations = current.EstimationIterations
+		projected[i].EstimationStdErr = current.EstimationStdErr
+		if !found || !utils.SameTableRow(current, projected[i]) {
+			report.Consistent = false
+			report.Discrepancies = append(report.Discrepancies, dto.StatsDiscrepancy{
+				TeamID:    projected[i].TeamID,
+				Stored:    current,
+				Projected: projected[i],
+			})
+		}
+	}
+	return report, projected, nil
+}
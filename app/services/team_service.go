@@ -8,19 +8,22 @@ import (
 type ITeamService interface {
 	GetTeamsByLeagueID(leagueID uint) ([]models.Team, error)
 	GetTeamByID(teamID uint) (models.Team, error)
+	GetTeamRatings(teamID uint) ([]models.TeamRating, error)
 }
 
 type TeamService struct {
-	teamRepo  repository.ITeamRepository
-	statsRepo repository.ITeamStatsRepository
+	teamRepo   repository.ITeamRepository
+	statsRepo  repository.ITeamStatsRepository
+	ratingRepo repository.ITeamRatingRepository
 }
 
 var _ ITeamService = &TeamService{}
 
-func NewTeamService(teamRepo repository.ITeamRepository, statsRepo repository.ITeamStatsRepository) *TeamService {
+func NewTeamService(teamRepo repository.ITeamRepository, statsRepo repository.ITeamStatsRepository, ratingRepo repository.ITeamRatingRepository) *TeamService {
 	return &TeamService{
-		teamRepo:  teamRepo,
-		statsRepo: statsRepo,
+		teamRepo:   teamRepo,
+		statsRepo:  statsRepo,
+		ratingRepo: ratingRepo,
 	}
 }
 func (s *TeamService) GetTeamsByLeagueID(leagueID uint) ([]models.Team, error) {
@@ -53,3 +56,10 @@ func (s *TeamService) GetTeamByID(teamID uint) (models.Team, error) {
 
 	return team, nil
 }
+
+func (s *TeamService) GetTeamRatings(teamID uint) ([]models.TeamRating, error) {
+	if _, err := s.teamRepo.GetTeamByID(teamID); err != nil {
+		return nil, err
+	}
+	return s.ratingRepo.GetRatingsByTeamID(teamID)
+}
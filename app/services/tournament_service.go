@@ -0,0 +1,379 @@
+package services
+
+import (
+	"fmt"
+	"insider-case/app/dto"
+	"insider-case/app/helpers"
+	"insider-case/app/models"
+	"insider-case/app/repository"
+	"insider-case/app/utils"
+)
+
+type ITournamentService interface {
+	CreateTournament(req dto.TournamentCreateRequest) (*dto.TournamentResponse, error)
+	GetTournament(tournamentID uint) (*dto.TournamentResponse, error)
+	SimulateRound(tournamentID uint) (*dto.TournamentResponse, error)
+	SimulateAll(tournamentID uint) (*dto.TournamentResponse, error)
+	GetProbabilities(tournamentID uint, req dto.EstimationRequest) (*dto.TournamentProjection, error)
+}
+
+type TournamentService struct {
+	repo          repository.ITournamentRepository
+	leagueRepo    repository.ILeagueRepository
+	tieRepo       repository.ITieRepository
+	leagueService ILeagueService
+	cupService    ICupService
+	uow           repository.IUnitOfWork
+}
+
+var _ ITournamentService = &TournamentService{}
+
+func NewTournamentService(repo repository.ITournamentRepository, leagueRepo repository.ILeagueRepository, tieRepo repository.ITieRepository, leagueService ILeagueService, cupService ICupService, uow repository.IUnitOfWork) *TournamentService {
+	return &TournamentService{
+		repo:          repo,
+		leagueRepo:    leagueRepo,
+		tieRepo:       tieRepo,
+		leagueService: leagueService,
+		cupService:    cupService,
+		uow:           uow,
+	}
+}
+
+// withRepositories returns a copy of the service working through the repositories of a unit of work
+func (s *TournamentService) withRepositories(repos repository.Repositories) *TournamentService {
+	return &TournamentService{
+		repo:          repos.Tournaments,
+		leagueRepo:    repos.Leagues,
+		tieRepo:       repos.Ties,
+		leagueService: s.leagueService.WithRepositories(repos),
+		cupService:    s.cupService.WithRepositories(repos),
+		uow:           repos.UnitOfWork,
+	}
+}
+
+func (s *TournamentService) CreateTournament(req dto.TournamentCreateRequest) (*dto.TournamentResponse, error) {
+	qualifiers := utils.DefaultQualifiers
+	if req.Qualifiers != nil {
+		qualifiers = *req.Qualifiers
+	}
+	if err := helpers.ValidateTournamentGroups(len(req.Teams), req.GroupCount, qualifiers); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateTeamStrength(req.Teams); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateEngine(req.Engine); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateEngineParams(req.EngineParams); err != nil {
+		return nil, err
+	}
+	if err := helpers.ValidateSimulationSettings(req.SimulationIterations, req.TargetStdErr); err != nil {
+		return nil, err
+	}
+	if req.SimulationIterations == 0 {
+		req.SimulationIterations = utils.DefaultSimulationIterations
+	}
+	if err := helpers.ValidateLegs(req.Legs); err != nil {
+		return nil, err
+	}
+	legs := utils.DefaultGroupLegs
+	if req.Legs != nil {
+		legs = *req.Legs
+	}
+	if err := helpers.ValidateKnockoutLegs(req.KnockoutLegs); err != nil {
+		return nil, err
+	}
+	knockoutLegs := utils.MinKnockoutLegs
+	if req.KnockoutLegs != nil {
+		knockoutLegs = *req.KnockoutLegs
+	}
+	if err := helpers.ValidateTieBreakers(req.TieBreakers); err != nil {
+		return nil, err
+	}
+	if len(req.TieBreakers) == 0 {
+		req.TieBreakers = utils.DefaultTieBreakers
+	}
+	points := pointsSystemFromRequest(req.Points)
+	if err := helpers.ValidatePointsSystem(points); err != nil {
+		return nil, err
+	}
+	if req.Engine == "" {
+		req.Engine = utils.DefaultEngine
+	}
+	seed := utils.GenerateSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	tournament := &models.Tournament{
+		Name:         req.Name,
+		GroupCount:   req.GroupCount,
+		Qualifiers:   qualifiers,
+		Seeded:       req.Seeded,
+		Seed:         seed,
+		KnockoutLegs: knockoutLegs,
+		AwayGoals:    req.AwayGoals,
+	}
+
+	teams := make([]models.Team, len(req.Teams))
+	for i, team := range req.Teams {
+		teams[i] = models.Team{
+			Name:     team.Name,
+			Strength: team.Strength,
+		}
+	}
+	drawn := utils.DrawGroups(teams, req.GroupCount, req.Seeded, seed)
+
+	groups := make([]*models.League, len(drawn))
+	for g, groupTeams := range drawn {
+		groups[g] = &models.League{
+			Name:         fmt.Sprintf("%s %s", req.Name, utils.GroupName(g)),
+			Format:       utils.FormatLeague,
+			TeamCount:    len(groupTeams),
+			MaxWeeks:     helpers.CalculateMaxWeeks(len(groupTeams), legs),
+			Legs:         legs,
+			Engine:       req.Engine,
+			EngineParams: engineParamsFromRequest(req.EngineParams),
+			UseRating:    req.UseRating,
+			Seed:         utils.GroupSeed(*tournament, g),
+
+			SimulationIterations: req.SimulationIterations,
+			TargetStdErr:         req.TargetStdErr,
+
+			TieBreakers: req.TieBreakers,
+			Points:      points,
+
+			Teams: groupTeams,
+		}
+	}
+
+	createdTournament, err := s.repo.InitializeTournament(tournament, groups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tournament: %w", err)
+	}
+	return s.tournamentResponse(createdTournament)
+}
+
+func (s *TournamentService) GetTournament(tournamentID uint) (*dto.TournamentResponse, error) {
+	tournament, err := s.repo.GetTournamentByID(tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament with ID %d: %w", tournamentID, err)
+	}
+	return s.tournamentResponse(tournament)
+}
+
+// SimulateRound plays the next week of every group, draws the knockout stage once the groups are
+// played, and then plays the knockout stage one round at a time. The round is committed as a whole
+// with the tournament locked, so concurrent requests play the rounds one after the other. The
+// estimations of the groups are published once the round is committed.
+func (s *TournamentService) SimulateRound(tournamentID uint) (*dto.TournamentResponse, error) {
+	var groupIDs []uint
+	err := s.uow.Do(func(repos repository.Repositories) error {
+		var err error
+		groupIDs, err = s.withRepositories(repos).simulateRound(tournamentID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, groupID := range groupIDs {
+		if err := s.leagueService.PublishEstimations(groupID); err != nil {
+			fmt.Printf("failed to publish estimations of league %d: %v\n", groupID, err)
+		}
+	}
+	return s.GetTournament(tournamentID)
+}
+
+// simulateRound plays the next round of the tournament and returns the groups that played a week
+func (s *TournamentService) simulateRound(tournamentID uint) ([]uint, error) {
+	tournament, err := s.repo.LockTournament(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if tournament.KnockoutID != nil {
+		if _, err := s.cupService.SimulateRound(*tournament.KnockoutID); err != nil {
+			return nil, fmt.Errorf("failed to simulate knockout round of tournament %d: %w", tournamentID, err)
+		}
+		return nil, nil
+	}
+
+	var groupIDs []uint
+	groupsPlayed := true
+	for _, group := range tournament.Groups {
+		if group.CurrWeek > group.MaxWeeks {
+			continue
+		}
+		week, err := s.leagueService.PlayWeek(group.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to simulate %s: %w", group.Name, err)
+		}
+		groupIDs = append(groupIDs, group.ID)
+		if week.Week <= group.MaxWeeks {
+			groupsPlayed = false
+		}
+	}
+	if groupsPlayed {
+		if err := s.drawKnockoutStage(tournament); err != nil {
+			return nil, err
+		}
+	}
+	return groupIDs, nil
+}
+
+// SimulateAll plays the rest of the tournament
+func (s *TournamentService) SimulateAll(tournamentID uint) (*dto.TournamentResponse, error) {
+	for {
+		response, err := s.GetTournament(tournamentID)
+		if err != nil {
+			return nil, err
+		}
+		if response.Stage == utils.StageFinished {
+			return response, nil
+		}
+		if _, err := s.SimulateRound(tournamentID); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// drawKnockoutStage seeds the knockout bracket from the qualifiers of the ranked groups
+func (s *TournamentService) drawKnockoutStage(tournament *models.Tournament) error {
+	qualifiers := make([][]uint, len(tournament.Groups))
+	for g, group := range tournament.Groups {
+		standings, err := s.leagueService.GetStandings(group.ID)
+		if err != nil {
+			return fmt.Errorf("failed to rank %s: %w", group.Name, err)
+		}
+		ranked := make([]models.TeamStats, len(standings.Table))
+		for i, entry := range standings.Table {
+			ranked[i] = entry.TeamStats
+		}
+		qualifiers[g] = utils.GroupQualifiers(ranked, tournament.Qualifiers)
+	}
+
+	first := tournament.Groups[0]
+	knockout := &models.League{
+		Name:         fmt.Sprintf("%s Knockout", tournament.Name),
+		Format:       utils.FormatKnockout,
+		TeamCount:    tournament.GroupCount * tournament.Qualifiers,
+		Legs:         tournament.KnockoutLegs,
+		AwayGoals:    tournament.AwayGoals,
+		Seeded:       true,
+		Engine:       first.Engine,
+		EngineParams: first.EngineParams,
+		UseRating:    first.UseRating,
+		Seed:         utils.KnockoutSeed(*tournament),
+
+		SimulationIterations: first.SimulationIterations,
+		TargetStdErr:         first.TargetStdErr,
+	}
+	if _, err := s.repo.InitializeKnockoutStage(tournament, knockout, utils.GroupStageBracket(qualifiers)); err != nil {
+		return fmt.Errorf("failed to draw knockout stage of tournament %d: %w", tournament.ID, err)
+	}
+	return nil
+}
+
+// GetProbabilities estimates the probability of every team qualifying, reaching each knockout round and winning the tournament
+func (s *TournamentService) GetProbabilities(tournamentID uint, req dto.EstimationRequest) (*dto.TournamentProjection, error) {
+	if err := helpers.ValidateSimulationSettings(req.Iterations, req.StdErr); err != nil {
+		return nil, err
+	}
+	tournament, err := s.repo.GetTournamentByID(tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament with ID %d: %w", tournamentID, err)
+	}
+
+	state := dto.TournamentState{
+		TournamentID: tournament.ID,
+		Qualifiers:   tournament.Qualifiers,
+		Groups:       make([]dto.TournamentGroupState, len(tournament.Groups)),
+	}
+	weeksPlayed := 0
+	for g, group := range tournament.Groups {
+		groupState, err := s.leagueService.GetLeagueState(group.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get state of %s: %w", group.Name, err)
+		}
+		state.Groups[g] = dto.TournamentGroupState{League: group, LeagueState: *groupState}
+		weeksPlayed = max(weeksPlayed, group.CurrWeek-1)
+	}
+
+	rules := utils.KnockoutRules{Legs: tournament.KnockoutLegs, AwayGoals: tournament.AwayGoals}
+	if tournament.KnockoutID != nil {
+		knockout, err := s.leagueRepo.GetLeagueByID(*tournament.KnockoutID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get knockout stage of tournament %d: %w", tournamentID, err)
+		}
+		ties, err := s.tieRepo.GetTiesByLeagueID(knockout.ID, utils.StageCup)
+		if err != nil {
+			return nil, err
+		}
+		state.Knockout = &dto.KnockoutState{
+			LeagueID: knockout.ID,
+			Round:    utils.KnockoutRound(knockout.CurrWeek, rules.Legs),
+			Rounds:   utils.KnockoutRounds(knockout.TeamCount),
+			Ties:     ties,
+		}
+		weeksPlayed += knockout.CurrWeek - 1
+	}
+
+	first := tournament.Groups[0]
+	engine, err := utils.NewMatchEngine(first)
+	if err != nil {
+		return nil, err
+	}
+	opts := utils.NewSimulationOptions(first, weeksPlayed)
+	opts.Seed = utils.DeriveSeed(tournament.Seed, int64(weeksPlayed))
+	if req.Iterations != 0 {
+		opts.Iterations = req.Iterations
+	}
+	if req.StdErr != 0 {
+		opts.TargetStdErr = req.StdErr
+	}
+
+	projection, err := utils.EstimateTournamentProbabilities(state, engine, rules, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate tournament probabilities: %w", err)
+	}
+	return projection, nil
+}
+
+func (s *TournamentService) tournamentResponse(tournament *models.Tournament) (*dto.TournamentResponse, error) {
+	response := &dto.TournamentResponse{
+		ID:         tournament.ID,
+		Name:       tournament.Name,
+		Stage:      utils.StageGroups,
+		Qualifiers: tournament.Qualifiers,
+		Seed:       tournament.Seed,
+		Groups:     make([]dto.TournamentGroup, len(tournament.Groups)),
+	}
+	for g, group := range tournament.Groups {
+		standings, err := s.leagueService.GetStandings(group.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to rank %s: %w", group.Name, err)
+		}
+		response.Groups[g] = dto.TournamentGroup{
+			LeagueID:  group.ID,
+			Name:      group.Name,
+			CurrWeek:  group.CurrWeek,
+			MaxWeeks:  group.MaxWeeks,
+			Standings: standings.Table,
+		}
+	}
+
+	if tournament.KnockoutID != nil {
+		bracket, err := s.cupService.GetBracket(*tournament.KnockoutID)
+		if err != nil {
+			return nil, err
+		}
+		response.Stage = utils.StageKnockout
+		response.Knockout = bracket
+		if bracket.Champion != nil {
+			response.Stage = utils.StageFinished
+			response.Champion = bracket.Champion
+		}
+	}
+	return response, nil
+}
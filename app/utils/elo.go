@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"math"
+	"math/rand"
+)
+
+const (
+	eloK             = 20.0
+	eloHomeAdvantage = 60.0 // rating points added to the home side when computing the expected result
+	eloScale         = 400.0
+)
+
+// UpdateElo returns the new ratings of both sides after a match. The rating
+// change is scaled by the goal difference as in the World Football Elo ratings.
+func UpdateElo(homeRating, awayRating float64, homeGoals, awayGoals int) (float64, float64) {
+	expectedHome := 1 / (1 + math.Pow(10, (awayRating-homeRating-eloHomeAdvantage)/eloScale))
+
+	actualHome := 0.5
+	if homeGoals > awayGoals {
+		actualHome = 1
+	} else if homeGoals < awayGoals {
+		actualHome = 0
+	}
+
+	change := eloK * goalDifferenceMultiplier(homeGoals-awayGoals) * (actualHome - expectedHome)
+	return homeRating + change, awayRating - change
+}
+
+// ReplayRatings replays the Elo updates of the played matches, ordered by week, from the initial
+// ratings of their teams. It returns the ratings every match produced and the final rating of every team.
+func ReplayRatings(initial map[uint]float64, matches []models.Match) ([]models.TeamRating, map[uint]float64) {
+	ratings := make(map[uint]float64, len(initial))
+	for teamID, rating := range initial {
+		ratings[teamID] = rating
+	}
+	var history []models.TeamRating
+	for _, match := range matches {
+		if !match.Played {
+			continue
+		}
+		matchID := match.ID
+		homeRating, awayRating := UpdateElo(ratings[match.HomeTeamID], ratings[match.AwayTeamID], match.HomeScore, match.AwayScore)
+		ratings[match.HomeTeamID], ratings[match.AwayTeamID] = homeRating, awayRating
+		history = append(history,
+			models.TeamRating{TeamID: match.HomeTeamID, LeagueID: match.LeagueID, Week: match.Week, MatchID: &matchID, Rating: homeRating},
+			models.TeamRating{TeamID: match.AwayTeamID, LeagueID: match.LeagueID, Week: match.Week, MatchID: &matchID, Rating: awayRating},
+		)
+	}
+	return history, ratings
+}
+
+func goalDifferenceMultiplier(goalDiff int) float64 {
+	if goalDiff < 0 {
+		goalDiff = -goalDiff
+	}
+	switch {
+	case goalDiff <= 1:
+		return 1
+	case goalDiff == 2:
+		return 1.5
+	default:
+		return (11 + float64(goalDiff)) / 8
+	}
+}
+
+// liveRatingEngine plays matches using the teams' live Elo ratings as their strength
+type liveRatingEngine struct {
+	engine MatchEngine
+}
+
+var _ MatchEngine = &liveRatingEngine{}
+
+func (e *liveRatingEngine) Play(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	return e.engine.Play(withRatingAsStrength(home), withRatingAsStrength(away), stats, r)
+}
+
+func (e *liveRatingEngine) PlayExtraTime(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	return e.engine.PlayExtraTime(withRatingAsStrength(home), withRatingAsStrength(away), stats, r)
+}
+
+func (e *liveRatingEngine) Shootout(home, away models.Team, r *rand.Rand) (int, int) {
+	return e.engine.Shootout(withRatingAsStrength(home), withRatingAsStrength(away), r)
+}
+
+func withRatingAsStrength(team models.Team) models.Team {
+	if team.Rating > 0 {
+		team.Strength = int(math.Round(team.Rating))
+	}
+	return team
+}
@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"fmt"
+	"insider-case/app/models"
+	"math/rand"
+	"sort"
+)
+
+const (
+	EngineClassic = "classic"
+	EnginePoisson = "poisson"
+	DefaultEngine = EnginePoisson
+
+	EventGoal       = "goal"
+	EventYellowCard = "yellow_card"
+	EventRedCard    = "red_card"
+
+	classicDrawChance = 0.2
+	matchMinutes      = 90
+	extraTimeMinutes  = 30
+
+	classicExtraTimeGoalChance = 0.35 // chance of a goal in extra time with the classic engine
+
+	yellowCardsPerTeam = 1.8  // expected yellow cards of a side in a match
+	redCardChance      = 0.08 // chance of a side getting a red card in a match
+)
+
+// MatchResult is the outcome of a single match played by a MatchEngine
+type MatchResult struct {
+	HomeGoals int
+	AwayGoals int
+	Events    []models.MatchEvent
+
+	HomePenalties *int // set when a level match was decided by a shootout
+	AwayPenalties *int
+	ExtraTime     bool // the goals include the ones scored in extra time
+}
+
+// MatchEngine decides the result of a match. The same engine is used for the
+// matches actually played and for the Monte Carlo estimations so the
+// estimations predict the engine that plays the games.
+type MatchEngine interface {
+	Play(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult
+	// PlayExtraTime plays the thirty minutes of extra time of a level knockout match
+	PlayExtraTime(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult
+	// Shootout decides a level match on penalties and returns the penalties scored by each side
+	Shootout(home, away models.Team, r *rand.Rand) (int, int)
+}
+
+// IsSupportedEngine reports whether name refers to a known match engine
+func IsSupportedEngine(name string) bool {
+	switch name {
+	case EngineClassic, EnginePoisson:
+		return true
+	}
+	return false
+}
+
+// NewMatchEngine returns the match engine configured for the league
+func NewMatchEngine(league models.League) (MatchEngine, error) {
+	var engine MatchEngine
+	switch league.Engine {
+	case "", EngineClassic:
+		engine = &ClassicEngine{}
+	case EnginePoisson:
+		engine = &PoissonEngine{params: league.EngineParams}
+	default:
+		return nil, fmt.Errorf("unknown match engine %q for league %d", league.Engine, league.ID)
+	}
+
+	if league.UseRating {
+		return &liveRatingEngine{engine: engine}, nil
+	}
+	return engine, nil
+}
+
+// PlayMatch plays a match with the engine and settles it on penalties when the rules do not allow draws
+func PlayMatch(engine MatchEngine, rules Rules, home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	result := engine.Play(home, away, stats, r)
+	if rules.Points.NoDraws && result.HomeGoals == result.AwayGoals {
+		homePenalties, awayPenalties := engine.Shootout(home, away, r)
+		result.HomePenalties = &homePenalties
+		result.AwayPenalties = &awayPenalties
+	}
+	return result
+}
+
+// ClassicEngine picks the match outcome first based on team strengths, home
+// advantage and form, then assigns random scores matching that outcome.
+type ClassicEngine struct{}
+
+var _ MatchEngine = &ClassicEngine{}
+
+func (e *ClassicEngine) Play(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	formFactor := CalculateFormFactor([]models.Team{home, away}, stats, home.ID)
+	if formFactor <= 0 {
+		formFactor = 1.0 // Default form factor if calculation fails
+	}
+
+	// Adjust win probability with form and home advantage
+	homeStrength := float64(home.Strength) * homeAdvantageMultiplier * formFactor
+	totalStrength := homeStrength + float64(away.Strength)
+	homeWinChance := homeStrength / totalStrength * (1 - classicDrawChance)
+
+	var homeGoals, awayGoals int
+	outcome := r.Float64()
+	switch {
+	case outcome < homeWinChance:
+		homeGoals = r.Intn(3) + 1 // 1 to 3
+		awayGoals = r.Intn(homeGoals)
+	case outcome < homeWinChance+classicDrawChance:
+		goals := r.Intn(3) // 0 to 2
+		homeGoals = goals
+		awayGoals = goals
+	default:
+		awayGoals = r.Intn(3) + 1 // 1 to 3
+		homeGoals = r.Intn(awayGoals)
+	}
+
+	return MatchResult{
+		HomeGoals: homeGoals,
+		AwayGoals: awayGoals,
+		Events:    matchEvents(home.ID, away.ID, homeGoals, awayGoals, r),
+	}
+}
+
+// PlayExtraTime gives a single goal a fixed chance, scored by either side in proportion to its strength
+func (e *ClassicEngine) PlayExtraTime(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	var homeGoals, awayGoals int
+	if r.Float64() < classicExtraTimeGoalChance {
+		homeStrength := float64(home.Strength) * homeAdvantageMultiplier
+		if r.Float64() < homeStrength/(homeStrength+float64(away.Strength)) {
+			homeGoals = 1
+		} else {
+			awayGoals = 1
+		}
+	}
+	return MatchResult{
+		HomeGoals: homeGoals,
+		AwayGoals: awayGoals,
+		Events:    extraTimeEvents(home.ID, away.ID, homeGoals, awayGoals, r),
+		ExtraTime: true,
+	}
+}
+
+func (e *ClassicEngine) Shootout(home, away models.Team, r *rand.Rand) (int, int) {
+	return penaltyShootout(home.Strength, away.Strength, r)
+}
+
+// matchEvents spreads the goals and the cards of a match over random minutes. The cards are
+// drawn after the goals so they do not change the scoreline drawn from the same seed.
+func matchEvents(homeID, awayID uint, homeGoals, awayGoals int, r *rand.Rand) []models.MatchEvent {
+	events := goalEvents(homeID, awayID, homeGoals, awayGoals, 0, matchMinutes, r)
+	for _, teamID := range []uint{homeID, awayID} {
+		yellowCards := samplePoisson(yellowCardsPerTeam, r)
+		for i := 0; i < yellowCards; i++ {
+			events = append(events, models.MatchEvent{Minute: r.Intn(matchMinutes) + 1, TeamID: teamID, Type: EventYellowCard})
+		}
+		if r.Float64() < redCardChance {
+			events = append(events, models.MatchEvent{Minute: r.Intn(matchMinutes) + 1, TeamID: teamID, Type: EventRedCard})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Minute < events[j].Minute
+	})
+	return events
+}
+
+// extraTimeEvents spreads the goals of extra time over minutes 91 to 120
+func extraTimeEvents(homeID, awayID uint, homeGoals, awayGoals int, r *rand.Rand) []models.MatchEvent {
+	return goalEvents(homeID, awayID, homeGoals, awayGoals, matchMinutes, extraTimeMinutes, r)
+}
+
+// goalEvents spreads the goals over the given number of minutes following minute after
+func goalEvents(homeID, awayID uint, homeGoals, awayGoals, after, minutes int, r *rand.Rand) []models.MatchEvent {
+	events := make([]models.MatchEvent, 0, homeGoals+awayGoals)
+	for i := 0; i < homeGoals; i++ {
+		events = append(events, models.MatchEvent{Minute: after + r.Intn(minutes) + 1, TeamID: homeID, Type: EventGoal})
+	}
+	for i := 0; i < awayGoals; i++ {
+		events = append(events, models.MatchEvent{Minute: after + r.Intn(minutes) + 1, TeamID: awayID, Type: EventGoal})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Minute < events[j].Minute
+	})
+	return events
+}
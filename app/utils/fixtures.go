@@ -0,0 +1,99 @@
+package utils
+
+const (
+	DefaultLegs = 2 // double round robin
+	MinLegs     = 1
+	MaxLegs     = 4
+)
+
+// Pairing is a match of a schedule between the teams at the Home and Away indexes
+type Pairing struct {
+	Home int
+	Away int
+}
+
+// Round is a round of a schedule. With an odd number of teams one team sits the round out.
+type Round struct {
+	Pairings []Pairing
+	Bye      *int // index of the team without a match, nil when every team plays
+}
+
+// RoundsPerLeg returns the number of rounds a single round robin between teamCount teams takes
+func RoundsPerLeg(teamCount int) int {
+	if teamCount%2 == 1 {
+		return teamCount
+	}
+	return teamCount - 1
+}
+
+// RoundRobin schedules a round robin between teamCount teams. An odd number of teams is
+// scheduled as one more team, and the team drawn against it has a bye that round. The
+// first leg is built with the circle method: the last team stays in place while the others
+// rotate, so every team meets every other team exactly once in teamCount-1 rounds. Venues
+// alternate along the circle, which keeps every team within one home game of its away games
+// and never has a team play more than two consecutive games at home or away.
+//
+// Every further leg mirrors the first with home and away swapped on every other leg. A leg
+// starts one round further into the first leg's rounds than the previous one: mirroring the
+// rounds in the same order would give three consecutive home or away games around the turn
+// of the legs, and the shift also keeps the same two teams from meeting in consecutive rounds.
+func RoundRobin(teamCount, legs int) []Round {
+	if teamCount < 2 || legs < 1 {
+		return nil
+	}
+	scheduled := teamCount
+	if teamCount%2 == 1 {
+		scheduled++ // the extra team stands for the bye
+	}
+	first := circleRounds(scheduled)
+	roundsPerLeg := len(first)
+
+	rounds := make([]Round, 0, roundsPerLeg*legs)
+	for leg := 0; leg < legs; leg++ {
+		for i := 0; i < roundsPerLeg; i++ {
+			var round Round
+			for _, pairing := range first[(i+leg)%roundsPerLeg] {
+				if leg%2 == 1 {
+					pairing = Pairing{Home: pairing.Away, Away: pairing.Home}
+				}
+				switch {
+				case pairing.Home == teamCount:
+					bye := pairing.Away
+					round.Bye = &bye
+				case pairing.Away == teamCount:
+					bye := pairing.Home
+					round.Bye = &bye
+				default:
+					round.Pairings = append(round.Pairings, pairing)
+				}
+			}
+			rounds = append(rounds, round)
+		}
+	}
+	return rounds
+}
+
+// circleRounds returns a single round robin between an even number of teams
+func circleRounds(teamCount int) [][]Pairing {
+	fixed := teamCount - 1 // the team that stays in place, the others rotate around it
+	rounds := make([][]Pairing, fixed)
+	for r := 0; r < fixed; r++ {
+		round := make([]Pairing, 0, teamCount/2)
+		if r%2 == 0 {
+			round = append(round, Pairing{Home: fixed, Away: r})
+		} else {
+			round = append(round, Pairing{Home: r, Away: fixed})
+		}
+		for k := 1; k < teamCount/2; k++ {
+			a := (r + k) % fixed
+			b := (r - k + fixed) % fixed
+			if k%2 == 1 {
+				round = append(round, Pairing{Home: a, Away: b})
+			} else {
+				round = append(round, Pairing{Home: b, Away: a})
+			}
+		}
+		rounds[r] = round
+	}
+	return rounds
+}
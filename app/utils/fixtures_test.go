@@ -0,0 +1,88 @@
+package utils
+
+import "testing"
+
+// TestRoundRobin checks the scheduling constraints of every supported team count and number of legs
+func TestRoundRobin(t *testing.T) {
+	for teamCount := 2; teamCount <= 21; teamCount++ {
+		for legs := MinLegs; legs <= MaxLegs; legs++ {
+			rounds := RoundRobin(teamCount, legs)
+			if len(rounds) != RoundsPerLeg(teamCount)*legs {
+				t.Errorf("%d teams, %d legs: got %d rounds, want %d", teamCount, legs, len(rounds), RoundsPerLeg(teamCount)*legs)
+				continue
+			}
+
+			meetings := make(map[[2]int]int)
+			hosted := make(map[[2]int]int) // games of every pair hosted by its first team
+			venues := make([][]bool, teamCount)
+			lastMet := make(map[[2]int]int)
+			for r, round := range rounds {
+				playing := make([]bool, teamCount)
+				play := func(team int) {
+					if team < 0 || team >= teamCount || playing[team] {
+						t.Fatalf("%d teams, %d legs: team %d scheduled twice or out of range in round %d", teamCount, legs, team, r+1)
+					}
+					playing[team] = true
+				}
+				for _, pairing := range round.Pairings {
+					play(pairing.Home)
+					play(pairing.Away)
+					pair := [2]int{min(pairing.Home, pairing.Away), max(pairing.Home, pairing.Away)}
+					meetings[pair]++
+					if pairing.Home == pair[0] {
+						hosted[pair]++
+					}
+					// two teams can only ever play each other
+					if last, ok := lastMet[pair]; ok && last == r-1 && teamCount > 2 {
+						t.Errorf("%d teams, %d legs: teams %d and %d meet in consecutive rounds %d and %d", teamCount, legs, pair[0], pair[1], r, r+1)
+					}
+					lastMet[pair] = r
+					venues[pairing.Home] = append(venues[pairing.Home], true)
+					venues[pairing.Away] = append(venues[pairing.Away], false)
+				}
+				if teamCount%2 == 1 {
+					if round.Bye == nil {
+						t.Fatalf("%d teams, %d legs: round %d has no bye", teamCount, legs, r+1)
+					}
+					play(*round.Bye)
+				} else if round.Bye != nil {
+					t.Errorf("%d teams, %d legs: round %d has a bye", teamCount, legs, r+1)
+				}
+			}
+
+			for a := 0; a < teamCount; a++ {
+				for b := a + 1; b < teamCount; b++ {
+					pair := [2]int{a, b}
+					if meetings[pair] != legs {
+						t.Errorf("%d teams, %d legs: teams %d and %d meet %d times", teamCount, legs, a, b, meetings[pair])
+					}
+					// the legs alternate the venue of every pair
+					if away := meetings[pair] - hosted[pair]; hosted[pair]-away > 1 || away-hosted[pair] > 1 {
+						t.Errorf("%d teams, %d legs: team %d hosts team %d %d times out of %d", teamCount, legs, a, b, hosted[pair], meetings[pair])
+					}
+				}
+			}
+
+			for team, games := range venues {
+				home, run := 0, 0
+				for g, atHome := range games {
+					if atHome {
+						home++
+					}
+					if g > 0 && atHome == games[g-1] {
+						run++
+					} else {
+						run = 1
+					}
+					if run > 2 {
+						t.Errorf("%d teams, %d legs: team %d plays %d consecutive games at the same venue up to game %d", teamCount, legs, team, run, g+1)
+						break
+					}
+				}
+				if away := len(games) - home; home-away > 1 || away-home > 1 {
+					t.Errorf("%d teams, %d legs: team %d plays %d home and %d away games", teamCount, legs, team, home, away)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package utils
+
+import (
+	"insider-case/app/dto"
+	"insider-case/app/models"
+	"math"
+)
+
+// eliminationSearchBudget bounds the number of scenarios explored when proving an elimination.
+// When the budget runs out the team is not reported as eliminated.
+const eliminationSearchBudget = 200000
+
+// outsidePoints are the points of the opponent outside the table standing in for unpaired rounds
+const outsidePoints = math.MinInt / 2
+
+// pointsOutcome is the number of points the home and away side can get from a single match
+type pointsOutcome struct {
+	home, away int
+}
+
+// AnalyzeGuarantees determines from the current standings and the remaining matches which
+// teams have mathematically clinched the title or can no longer win it, and the range of
+// positions each team can still finish in. Ties on points are always counted against the
+// team, so every reported guarantee holds whatever the tie-breakers decide. unpaired counts the
+// rounds whose opponents are not paired yet, as in a Swiss-system league: every team plays them
+// against an opponent outside the table, which allows any result and keeps the guarantees safe.
+func AnalyzeGuarantees(stats []models.TeamStats, remaining []models.Match, unpaired int, points models.PointsSystem) []dto.TeamGuarantee {
+	a := newGuaranteeAnalysis(stats, remaining, unpaired, points)
+
+	guarantees := make([]dto.TeamGuarantee, len(stats))
+	for x := range stats {
+		guarantee := dto.TeamGuarantee{
+			TeamID:                  stats[x].TeamID,
+			HighestPossiblePosition: 1,
+			LowestPossiblePosition:  1,
+		}
+		for y := range stats {
+			if y == x {
+				continue
+			}
+			// y can finish level with or above x
+			if a.maxLead(y, x) >= 0 {
+				guarantee.LowestPossiblePosition++
+			}
+			// y finishes above x in every scenario
+			if a.minLead(y, x) > 0 {
+				guarantee.HighestPossiblePosition++
+			}
+		}
+
+		guarantee.ClinchedTitle = guarantee.LowestPossiblePosition == 1
+		guarantee.Eliminated = guarantee.HighestPossiblePosition > 1 || !a.canFinishTop(x)
+		if guarantee.Eliminated {
+			guarantee.HighestPossiblePosition = max(guarantee.HighestPossiblePosition, 2)
+		}
+		guarantee.MagicNumber = a.magicNumber(x, guarantee.ClinchedTitle)
+		guarantees[x] = guarantee
+	}
+	return guarantees
+}
+
+// LeagueGuarantees analyzes the guarantees of the league's teams. When the title is decided by a
+// playoff, finishing top of the regular season clinches nothing: a team is only eliminated once it
+// can no longer reach the playoff or loses a playoff tie, and only the winner of the final clinches.
+func LeagueGuarantees(leagueState dto.LeagueState, rules Rules) []dto.TeamGuarantee {
+	guarantees := AnalyzeGuarantees(leagueState.TeamStats, leagueState.RemainingMatches, SwissRoundsToPair(leagueState, rules), rules.Points)
+	if rules.Playoffs.Teams == 0 {
+		return guarantees
+	}
+
+	losers := make(map[uint]bool)
+	for _, tie := range leagueState.PlayoffTies {
+		if tie.WinnerID == nil {
+			continue
+		}
+		for _, teamID := range []*uint{tie.HomeTeamID, tie.AwayTeamID} {
+			if teamID != nil && *teamID != *tie.WinnerID {
+				losers[*teamID] = true
+			}
+		}
+	}
+	champion := PlayoffWinner(leagueState.PlayoffTies, TitlePlayoffRounds(rules.Playoffs))
+	for i := range guarantees {
+		guarantee := &guarantees[i]
+		guarantee.ClinchedTitle = champion != nil && *champion == guarantee.TeamID
+		guarantee.Eliminated = guarantee.HighestPossiblePosition > rules.Playoffs.Teams || losers[guarantee.TeamID] ||
+			(champion != nil && !guarantee.ClinchedTitle)
+		guarantee.MagicNumber = nil
+	}
+	return guarantees
+}
+
+// ApplyGuarantees attaches the guarantees to the estimations of the same teams
+func ApplyGuarantees(estimations []dto.ChampionshipEstimation, guarantees []dto.TeamGuarantee) {
+	byTeam := make(map[uint]dto.TeamGuarantee, len(guarantees))
+	for _, guarantee := range guarantees {
+		byTeam[guarantee.TeamID] = guarantee
+	}
+	for i := range estimations {
+		guarantee, ok := byTeam[estimations[i].TeamID]
+		if !ok {
+			continue
+		}
+		estimations[i].Guarantee = &guarantee
+		switch {
+		case guarantee.ClinchedTitle:
+			estimations[i].Status = dto.StatusClinched
+		case guarantee.Eliminated:
+			estimations[i].Status = dto.StatusEliminated
+		default:
+			estimations[i].Status = dto.StatusContending
+		}
+	}
+}
+
+type guaranteeAnalysis struct {
+	points    []int
+	matches   [][2]int // remaining matches as indexes of the home and away team
+	outcomes  []pointsOutcome
+	bestGain  int // most points a team can get from a single match
+	worstGain int // fewest points a team can get from a single match
+	budget    int // scenarios canFinishTop explores before giving up
+}
+
+func newGuaranteeAnalysis(stats []models.TeamStats, remaining []models.Match, unpaired int, points models.PointsSystem) *guaranteeAnalysis {
+	index := make(map[uint]int, len(stats))
+	a := &guaranteeAnalysis{
+		points:   make([]int, len(stats)),
+		outcomes: pointsOutcomes(points),
+		budget:   eliminationSearchBudget,
+	}
+	for i, stat := range stats {
+		index[stat.TeamID] = i
+		a.points[i] = stat.Points
+	}
+	for _, match := range remaining {
+		if match.Played {
+			continue
+		}
+		home, homeOK := index[match.HomeTeamID]
+		away, awayOK := index[match.AwayTeamID]
+		if homeOK && awayOK {
+			a.matches = append(a.matches, [2]int{home, away})
+		}
+	}
+	if unpaired > 0 {
+		// The opponent outside the table is so far below it that it never finishes above a team
+		outside := len(a.points)
+		a.points = append(a.points, outsidePoints)
+		for i := range stats {
+			for round := 0; round < unpaired; round++ {
+				a.matches = append(a.matches, [2]int{i, outside})
+			}
+		}
+	}
+
+	a.bestGain, a.worstGain = a.outcomes[0].home, a.outcomes[0].home
+	for _, outcome := range a.outcomes {
+		a.bestGain = max(a.bestGain, outcome.home, outcome.away)
+		a.worstGain = min(a.worstGain, outcome.home, outcome.away)
+	}
+	return a
+}
+
+// maxLead is the largest number of points y can finish ahead of x
+func (a *guaranteeAnalysis) maxLead(y, x int) int {
+	return a.lead(y, x, true)
+}
+
+// minLead is the smallest number of points y can finish ahead of x
+func (a *guaranteeAnalysis) minLead(y, x int) int {
+	return a.lead(y, x, false)
+}
+
+func (a *guaranteeAnalysis) lead(y, x int, maximize bool) int {
+	lead := a.points[y] - a.points[x]
+	for _, match := range a.matches {
+		yPlays := match[0] == y || match[1] == y
+		xPlays := match[0] == x || match[1] == x
+		if !yPlays && !xPlays {
+			continue
+		}
+
+		best := 0
+		for i, outcome := range a.outcomes {
+			diff := 0
+			if yPlays {
+				diff += sidePoints(outcome, match, y)
+			}
+			if xPlays {
+				diff -= sidePoints(outcome, match, x)
+			}
+			if i == 0 || (maximize && diff > best) || (!maximize && diff < best) {
+				best = diff
+			}
+		}
+		lead += best
+	}
+	return lead
+}
+
+// canFinishTop searches for a scenario in which no team finishes above x on points
+func (a *guaranteeAnalysis) canFinishTop(x int) bool {
+	// Matches of x are decided first so the remaining search knows x's final points
+	ordered := make([][2]int, 0, len(a.matches))
+	for _, match := range a.matches {
+		if match[0] == x || match[1] == x {
+			ordered = append(ordered, match)
+		}
+	}
+	ownMatches := len(ordered)
+	for _, match := range a.matches {
+		if match[0] != x && match[1] != x {
+			ordered = append(ordered, match)
+		}
+	}
+
+	left := make([]int, len(a.points)) // remaining matches of each team in the search
+	for _, match := range ordered {
+		left[match[0]]++
+		left[match[1]]++
+	}
+
+	points := make([]int, len(a.points))
+	copy(points, a.points)
+	budget := a.budget
+
+	var search func(i int) bool
+	search = func(i int) bool {
+		budget--
+		if budget < 0 {
+			return true // undecided, do not claim an elimination
+		}
+		if i >= ownMatches {
+			// x's points are final, every other team must be able to stay at or below them
+			for t := range points {
+				if t != x && points[t]+left[t]*a.worstGain > points[x] {
+					return false
+				}
+			}
+		}
+		if i == len(ordered) {
+			return true
+		}
+
+		match := ordered[i]
+		left[match[0]]--
+		left[match[1]]--
+		for _, outcome := range a.orderedOutcomes(match, x, points) {
+			points[match[0]] += outcome.home
+			points[match[1]] += outcome.away
+			found := search(i + 1)
+			points[match[0]] -= outcome.home
+			points[match[1]] -= outcome.away
+			if found {
+				left[match[0]]++
+				left[match[1]]++
+				return true
+			}
+		}
+		left[match[0]]++
+		left[match[1]]++
+		return false
+	}
+
+	return search(0)
+}
+
+// orderedOutcomes orders the outcomes of a match so the search tries the most promising ones
+// first: the ones most favourable to x in its own matches, otherwise the ones giving the points
+// to the side further below x.
+func (a *guaranteeAnalysis) orderedOutcomes(match [2]int, x int, points []int) []pointsOutcome {
+	favoured := x
+	if match[0] != x && match[1] != x {
+		favoured = match[0]
+		if points[match[1]] < points[match[0]] {
+			favoured = match[1]
+		}
+	}
+
+	ordered := make([]pointsOutcome, len(a.outcomes))
+	copy(ordered, a.outcomes)
+	for i := 1; i < len(ordered); i++ {
+		for j := i; j > 0 && sidePoints(ordered[j], match, favoured) > sidePoints(ordered[j-1], match, favoured); j-- {
+			ordered[j], ordered[j-1] = ordered[j-1], ordered[j]
+		}
+	}
+	return ordered
+}
+
+// magicNumber is the number of points x needs from its own remaining matches to clinch the
+// title even if every rival wins all of its remaining matches. It is nil when x cannot
+// clinch the title on its own.
+func (a *guaranteeAnalysis) magicNumber(x int, clinched bool) *int {
+	if clinched {
+		zero := 0
+		return &zero
+	}
+
+	rivalBest := 0
+	ownGain := 0
+	for t := range a.points {
+		if t == x {
+			continue
+		}
+		best := a.points[t]
+		for _, match := range a.matches {
+			if match[0] == t || match[1] == t {
+				best += a.bestGain
+			}
+		}
+		rivalBest = max(rivalBest, best)
+	}
+	for _, match := range a.matches {
+		if match[0] == x || match[1] == x {
+			ownGain += a.bestGain
+		}
+	}
+
+	magic := max(rivalBest-a.points[x]+1, 0)
+	if magic > ownGain {
+		return nil
+	}
+	return &magic
+}
+
+// sidePoints returns the points the given team gets from the outcome of the match
+func sidePoints(outcome pointsOutcome, match [2]int, team int) int {
+	if match[0] == team {
+		return outcome.home
+	}
+	return outcome.away
+}
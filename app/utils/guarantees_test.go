@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"testing"
+)
+
+// guaranteeStats returns the stats of teams 1 to n with the given points
+func guaranteeStats(points ...int) []models.TeamStats {
+	stats := make([]models.TeamStats, len(points))
+	for i, p := range points {
+		stats[i] = models.TeamStats{TeamID: uint(i + 1), Points: p}
+	}
+	return stats
+}
+
+// unplayed returns unplayed matches between the given home and away team IDs
+func unplayed(pairs ...[2]uint) []models.Match {
+	matches := make([]models.Match, len(pairs))
+	for i, pair := range pairs {
+		matches[i] = models.Match{ID: uint(i + 1), HomeTeamID: pair[0], AwayTeamID: pair[1]}
+	}
+	return matches
+}
+
+type guaranteeWant struct {
+	clinched, eliminated bool
+	highest, lowest      int
+}
+
+func TestAnalyzeGuarantees(t *testing.T) {
+	rugby := models.PointsSystem{
+		Win: 4, Draw: 2, Loss: 0,
+		GoalsBonusThreshold: 4, GoalsBonus: 1,
+		LosingBonusMargin: 7, LosingBonus: 1,
+	}
+	noDraws := models.PointsSystem{Win: 3, Loss: 0, NoDraws: true, ShootoutWin: 2, ShootoutLoss: 1}
+
+	tests := []struct {
+		name      string
+		points    []int
+		remaining []models.Match
+		unpaired  int
+		system    models.PointsSystem
+		want      []guaranteeWant
+	}{
+		{
+			name:   "season over",
+			points: []int{10, 8, 8},
+			system: DefaultPointsSystem(),
+			want: []guaranteeWant{
+				{clinched: true, highest: 1, lowest: 1},
+				{eliminated: true, highest: 2, lowest: 3},
+				{eliminated: true, highest: 2, lowest: 3},
+			},
+		},
+		{
+			// the rival can only draw level on points, which head-to-head or another tie-breaker decides
+			name:      "level on points left to the tie-breakers",
+			points:    []int{10, 7, 0},
+			remaining: unplayed([2]uint{2, 3}),
+			system:    DefaultPointsSystem(),
+			want: []guaranteeWant{
+				{highest: 1, lowest: 2},
+				{highest: 1, lowest: 2},
+				{eliminated: true, highest: 3, lowest: 3},
+			},
+		},
+		{
+			name:      "eliminated by the rivals playing each other",
+			points:    []int{6, 6, 6},
+			remaining: unplayed([2]uint{2, 3}),
+			system:    DefaultPointsSystem(),
+			want: []guaranteeWant{
+				{eliminated: true, highest: 2, lowest: 3},
+				{highest: 1, lowest: 3},
+				{highest: 1, lowest: 3},
+			},
+		},
+		{
+			name:   "swiss round still to pair",
+			points: []int{10, 8},
+			// every team plays the unpaired round against an opponent outside the table
+			unpaired: 1,
+			system:   DefaultPointsSystem(),
+			want: []guaranteeWant{
+				{highest: 1, lowest: 2},
+				{highest: 1, lowest: 2},
+			},
+		},
+		{
+			name:   "swiss rounds all played",
+			points: []int{10, 8},
+			system: DefaultPointsSystem(),
+			want: []guaranteeWant{
+				{clinched: true, highest: 1, lowest: 1},
+				{eliminated: true, highest: 2, lowest: 2},
+			},
+		},
+		{
+			name:      "bonus points keep the chaser level",
+			points:    []int{20, 15},
+			remaining: unplayed([2]uint{1, 2}),
+			system:    rugby,
+			want: []guaranteeWant{
+				{highest: 1, lowest: 2},
+				{highest: 1, lowest: 2},
+			},
+		},
+		{
+			name:      "bonus points out of reach",
+			points:    []int{20, 14},
+			remaining: unplayed([2]uint{1, 2}),
+			system:    rugby,
+			want: []guaranteeWant{
+				{clinched: true, highest: 1, lowest: 1},
+				{eliminated: true, highest: 2, lowest: 2},
+			},
+		},
+		{
+			name:      "draw keeps the leader level",
+			points:    []int{10, 9, 9},
+			remaining: unplayed([2]uint{2, 3}),
+			system:    DefaultPointsSystem(),
+			want: []guaranteeWant{
+				{highest: 1, lowest: 3},
+				{highest: 1, lowest: 3},
+				{highest: 1, lowest: 3},
+			},
+		},
+		{
+			name:      "no draws puts a rival above the leader",
+			points:    []int{10, 9, 9},
+			remaining: unplayed([2]uint{2, 3}),
+			system:    noDraws,
+			want: []guaranteeWant{
+				{eliminated: true, highest: 2, lowest: 3},
+				{highest: 1, lowest: 3},
+				{highest: 1, lowest: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guarantees := AnalyzeGuarantees(guaranteeStats(tt.points...), tt.remaining, tt.unpaired, tt.system)
+			if len(guarantees) != len(tt.want) {
+				t.Fatalf("got %d guarantees, want %d", len(guarantees), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := guarantees[i]
+				if got.TeamID != uint(i+1) {
+					t.Errorf("guarantee %d is for team %d", i, got.TeamID)
+				}
+				if got.ClinchedTitle != want.clinched || got.Eliminated != want.eliminated ||
+					got.HighestPossiblePosition != want.highest || got.LowestPossiblePosition != want.lowest {
+					t.Errorf("team %d: got clinched=%t eliminated=%t positions %d-%d, want clinched=%t eliminated=%t positions %d-%d",
+						got.TeamID, got.ClinchedTitle, got.Eliminated, got.HighestPossiblePosition, got.LowestPossiblePosition,
+						want.clinched, want.eliminated, want.highest, want.lowest)
+				}
+			}
+		})
+	}
+}
+
+func TestMagicNumber(t *testing.T) {
+	stats := guaranteeStats(10, 8, 0)
+	remaining := unplayed([2]uint{1, 3}, [2]uint{2, 3})
+	guarantees := AnalyzeGuarantees(stats, remaining, 0, DefaultPointsSystem())
+
+	// team 2 can reach 11 by beating team 3, team 1 needs 12
+	if magic := guarantees[0].MagicNumber; magic == nil || *magic != 2 {
+		t.Errorf("team 1: got magic number %v, want 2", magic)
+	}
+	// team 1 can reach 13, beyond the 11 team 2 can reach
+	if magic := guarantees[1].MagicNumber; magic != nil {
+		t.Errorf("team 2: got magic number %d, want none", *magic)
+	}
+	if magic := guarantees[2].MagicNumber; magic != nil {
+		t.Errorf("team 3: got magic number %d, want none", *magic)
+	}
+
+	clinched := AnalyzeGuarantees(guaranteeStats(10, 8), nil, 0, DefaultPointsSystem())
+	if magic := clinched[0].MagicNumber; magic == nil || *magic != 0 {
+		t.Errorf("clinched team: got magic number %v, want 0", magic)
+	}
+}
+
+func TestCanFinishTopSearchBudget(t *testing.T) {
+	// team 1 is out of the title race only because one of its two rivals wins their match
+	stats := guaranteeStats(6, 6, 6)
+	remaining := unplayed([2]uint{2, 3})
+
+	a := newGuaranteeAnalysis(stats, remaining, 0, DefaultPointsSystem())
+	if a.canFinishTop(0) {
+		t.Fatal("team 1 can finish top with the full budget")
+	}
+
+	a = newGuaranteeAnalysis(stats, remaining, 0, DefaultPointsSystem())
+	a.budget = 1
+	if !a.canFinishTop(0) {
+		t.Error("an exhausted search must not prove an elimination")
+	}
+}
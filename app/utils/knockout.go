@@ -0,0 +1,438 @@
+package utils
+
+import (
+	"fmt"
+	"insider-case/app/dto"
+	"insider-case/app/models"
+	"math/bits"
+	"math/rand"
+	"sort"
+)
+
+const (
+	FormatLeague   = "league"
+	FormatKnockout = "knockout"
+
+	StageCup = "cup"
+
+	DecidedByBye       = "bye"
+	DecidedByScore     = "score"
+	DecidedByAwayGoals = "away_goals"
+	DecidedByExtraTime = "extra_time"
+	DecidedByPenalties = "penalties"
+
+	MinKnockoutLegs = 1
+	MaxKnockoutLegs = 2
+)
+
+// KnockoutRules are the rules of the ties of a knockout competition
+type KnockoutRules struct {
+	Legs      int
+	AwayGoals bool // away goals break a level aggregate of a two-legged tie
+}
+
+// NewKnockoutRules returns the knockout rules configured for the league
+func NewKnockoutRules(league models.League) KnockoutRules {
+	legs := league.Legs
+	if legs < MinKnockoutLegs {
+		legs = MinKnockoutLegs
+	}
+	return KnockoutRules{
+		Legs:      legs,
+		AwayGoals: league.AwayGoals,
+	}
+}
+
+// KnockoutRounds returns the number of rounds of a bracket of teamCount teams
+func KnockoutRounds(teamCount int) int {
+	if teamCount < 2 {
+		return 0
+	}
+	return bits.Len(uint(teamCount - 1))
+}
+
+// KnockoutRound returns the round a knockout competition plays in the given week
+func KnockoutRound(week int, legs int) int {
+	return (week-1)/legs + 1
+}
+
+// SeedBracket places the teams on a bracket and returns the ties of the first round. Seeded
+// brackets order the teams by strength, the others are drawn with the seed. The top seeds
+// are kept apart until the late rounds and get the byes when the team count is not a power
+// of two.
+func SeedBracket(teams []models.Team, seeded bool, seed int64) []models.Tie {
+	ordered := make([]models.Team, len(teams))
+	copy(ordered, teams)
+	if seeded {
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if ordered[i].Strength != ordered[j].Strength {
+				return ordered[i].Strength > ordered[j].Strength
+			}
+			return ordered[i].ID < ordered[j].ID
+		})
+	} else {
+		rng := NewRand(seed)
+		rng.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+	}
+
+	ids := make([]uint, len(ordered))
+	for i, team := range ordered {
+		ids[i] = team.ID
+	}
+	return BracketFromSeeds(ids)
+}
+
+// BracketFromSeeds returns the first round ties of a bracket of the teams in seed order
+func BracketFromSeeds(seeds []uint) []models.Tie {
+	size := 1 << KnockoutRounds(len(seeds))
+	positions := bracketPositions(size)
+
+	ties := make([]models.Tie, 0, size/2)
+	for slot := 0; slot < size/2; slot++ {
+		tie := models.Tie{Stage: StageCup, Round: 1, Slot: slot}
+		home, away := positions[2*slot], positions[2*slot+1]
+		if home < len(seeds) {
+			tie.HomeTeamID = &seeds[home]
+		}
+		if away < len(seeds) {
+			tie.AwayTeamID = &seeds[away]
+		}
+		if tie.HomeTeamID == nil {
+			// Only the away side is drawn, it hosts its bye
+			tie.HomeTeamID, tie.AwayTeamID = tie.AwayTeamID, nil
+		}
+		if tie.AwayTeamID == nil {
+			tie.WinnerID = tie.HomeTeamID
+			tie.DecidedBy = DecidedByBye
+		}
+		ties = append(ties, tie)
+	}
+	return ties
+}
+
+// bracketPositions returns the seed placed on every position of a bracket of the given
+// size, so that seeds 1 and 2 can only meet in the final, seeds 1 to 4 in the semi-finals...
+func bracketPositions(size int) []int {
+	positions := []int{0}
+	for len(positions) < size {
+		next := make([]int, 0, 2*len(positions))
+		for _, seed := range positions {
+			next = append(next, seed, 2*len(positions)-1-seed)
+		}
+		positions = next
+	}
+	return positions
+}
+
+// NextRoundTies pairs the winners of a decided round, the winners of slots 2i and 2i+1 meet in slot i
+func NextRoundTies(round []models.Tie) ([]models.Tie, error) {
+	sorted := make([]models.Tie, len(round))
+	copy(sorted, round)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Slot < sorted[j].Slot
+	})
+
+	next := make([]models.Tie, 0, len(sorted)/2)
+	for i := 0; i+1 < len(sorted); i += 2 {
+		home, away := sorted[i], sorted[i+1]
+		if home.WinnerID == nil || away.WinnerID == nil {
+			return nil, fmt.Errorf("round %d is not decided yet", home.Round)
+		}
+		next = append(next, models.Tie{
+			LeagueID:   home.LeagueID,
+			Stage:      home.Stage,
+			Round:      home.Round + 1,
+			Slot:       i / 2,
+			HomeTeamID: home.WinnerID,
+			AwayTeamID: away.WinnerID,
+		})
+	}
+	return next, nil
+}
+
+// TieMatches returns the legs of a tie, played from firstWeek on. The home team of the tie hosts the first leg.
+func TieMatches(league models.League, rules KnockoutRules, tie models.Tie, firstWeek int) []models.Match {
+	if tie.HomeTeamID == nil || tie.AwayTeamID == nil {
+		return nil
+	}
+	matches := make([]models.Match, 0, rules.Legs)
+	for leg := 1; leg <= rules.Legs; leg++ {
+		week := firstWeek + leg - 1
+		home, away := *tie.HomeTeamID, *tie.AwayTeamID
+		if leg%2 == 0 {
+			home, away = away, home
+		}
+		tieID := tie.ID
+		matches = append(matches, models.Match{
+			LeagueID:   league.ID,
+			Week:       week,
+			HomeTeamID: home,
+			AwayTeamID: away,
+			Seed:       DeriveSeed(league.Seed, int64(week), int64(tie.Slot), stageSeedSalt(tie.Stage)),
+			TieID:      &tieID,
+			Leg:        leg,
+		})
+	}
+	return matches
+}
+
+// stageSeedSalt separates the seeds of ties of different stages played in the same week
+func stageSeedSalt(stage string) int64 {
+	switch stage {
+	case StagePlayoff:
+		return 1
+	case StageRelegation:
+		return 2
+	}
+	return 0
+}
+
+// TieOutcome is the outcome of a knockout tie. Legs are ordered as played, the first one
+// hosted by the home team of the tie; aggregates and penalties are from the tie's point of view.
+type TieOutcome struct {
+	Legs          []MatchResult
+	HomeAggregate int
+	AwayAggregate int
+	HomePenalties *int
+	AwayPenalties *int
+	HomeWins      bool
+	DecidedBy     string
+}
+
+// PlayTie plays every leg of a tie. A level tie goes to the away goals rule when it applies,
+// then to extra time at the end of the last leg, and finally to a penalty shootout.
+func PlayTie(engine MatchEngine, rules KnockoutRules, home, away models.Team, stats []models.TeamStats, r *rand.Rand) TieOutcome {
+	var outcome TieOutcome
+	homeAwayGoals, awayAwayGoals := 0, 0
+
+	for leg := 0; leg < rules.Legs; leg++ {
+		host, guest := home, away
+		if leg%2 == 1 {
+			host, guest = away, home
+		}
+		result := engine.Play(host, guest, stats, r)
+
+		if leg == rules.Legs-1 {
+			homeGoals, awayGoals := orientTo(leg, result)
+			if outcome.HomeAggregate+homeGoals == outcome.AwayAggregate+awayGoals &&
+				!awayGoalsDecide(rules, homeAwayGoals, awayAwayGoals, leg, result) {
+				extraTime := engine.PlayExtraTime(host, guest, stats, r)
+				result.HomeGoals += extraTime.HomeGoals
+				result.AwayGoals += extraTime.AwayGoals
+				result.Events = append(result.Events, extraTime.Events...)
+				result.ExtraTime = true
+			}
+		}
+
+		homeGoals, awayGoals := orientTo(leg, result)
+		outcome.HomeAggregate += homeGoals
+		outcome.AwayAggregate += awayGoals
+		if leg%2 == 1 {
+			homeAwayGoals += result.AwayGoals
+		} else {
+			awayAwayGoals += result.AwayGoals
+		}
+
+		if leg == rules.Legs-1 {
+			outcome.decide(rules, homeAwayGoals, awayAwayGoals, &result, engine, host, guest, leg, r)
+		}
+		outcome.Legs = append(outcome.Legs, result)
+	}
+	return outcome
+}
+
+// decide settles the winner of the tie once the last leg is played, adding a shootout to it when needed
+func (o *TieOutcome) decide(rules KnockoutRules, homeAwayGoals, awayAwayGoals int, last *MatchResult, engine MatchEngine, host, guest models.Team, leg int, r *rand.Rand) {
+	switch {
+	case o.HomeAggregate != o.AwayAggregate:
+		o.HomeWins = o.HomeAggregate > o.AwayAggregate
+		o.DecidedBy = DecidedByScore
+		if last.ExtraTime {
+			o.DecidedBy = DecidedByExtraTime
+		}
+	case rules.AwayGoals && rules.Legs > 1 && homeAwayGoals != awayAwayGoals:
+		o.HomeWins = homeAwayGoals > awayAwayGoals
+		o.DecidedBy = DecidedByAwayGoals
+	default:
+		hostPenalties, guestPenalties := engine.Shootout(host, guest, r)
+		last.HomePenalties, last.AwayPenalties = &hostPenalties, &guestPenalties
+		homePenalties, awayPenalties := hostPenalties, guestPenalties
+		if leg%2 == 1 {
+			homePenalties, awayPenalties = guestPenalties, hostPenalties
+		}
+		o.HomePenalties, o.AwayPenalties = &homePenalties, &awayPenalties
+		o.HomeWins = homePenalties > awayPenalties
+		o.DecidedBy = DecidedByPenalties
+	}
+}
+
+// awayGoalsDecide reports whether the away goals rule decides a tie level on aggregate after the given leg
+func awayGoalsDecide(rules KnockoutRules, homeAwayGoals, awayAwayGoals, leg int, result MatchResult) bool {
+	if !rules.AwayGoals || rules.Legs < 2 {
+		return false
+	}
+	if leg%2 == 1 {
+		homeAwayGoals += result.AwayGoals
+	} else {
+		awayAwayGoals += result.AwayGoals
+	}
+	return homeAwayGoals != awayAwayGoals
+}
+
+// orientTo returns the goals of a leg from the point of view of the tie's home team
+func orientTo(leg int, result MatchResult) (int, int) {
+	if leg%2 == 1 {
+		return result.AwayGoals, result.HomeGoals
+	}
+	return result.HomeGoals, result.AwayGoals
+}
+
+// knockoutAccumulator counts how often every team reached every round, indexed like the state's teams
+type knockoutAccumulator struct {
+	reached [][]int // reached[team][round-1], the last entry counts the cups won
+}
+
+func newKnockoutAccumulator(teamCount, rounds int) *knockoutAccumulator {
+	acc := &knockoutAccumulator{reached: make([][]int, teamCount)}
+	for i := range acc.reached {
+		acc.reached[i] = make([]int, rounds+1)
+	}
+	return acc
+}
+
+func (acc *knockoutAccumulator) merge(part *knockoutAccumulator) {
+	for i := range part.reached {
+		for round, count := range part.reached[i] {
+			acc.reached[i][round] += count
+		}
+	}
+}
+
+// winStdErr is the largest standard error of the teams' probabilities of winning the competition
+func (acc *knockoutAccumulator) winStdErr(iterations int) float64 {
+	worst := 0.0
+	for i := range acc.reached {
+		worst = max(worst, proportionStdErr(acc.reached[i][len(acc.reached[i])-1], iterations))
+	}
+	return worst
+}
+
+// EstimateKnockoutProbabilities runs Monte Carlo simulations of the rest of a knockout
+// competition and returns the probability of every team reaching each round and of winning it.
+func EstimateKnockoutProbabilities(state dto.KnockoutState, engine MatchEngine, rules KnockoutRules, opts SimulationOptions) (*dto.KnockoutProjection, error) {
+	if len(state.Teams) == 0 {
+		return nil, fmt.Errorf("no teams provided")
+	}
+	if opts.Iterations <= 0 {
+		return nil, fmt.Errorf("iteration count must be greater than 0")
+	}
+
+	teamIndex := make(map[uint]int, len(state.Teams))
+	for i, team := range state.Teams {
+		teamIndex[team.ID] = i
+	}
+
+	baseReached := make([]int, len(state.Teams))
+	current := bracketProgress(state, teamIndex, baseReached)
+
+	newAcc := func() *knockoutAccumulator {
+		return newKnockoutAccumulator(len(state.Teams), state.Rounds)
+	}
+	iterate := func(r *rand.Rand, acc *knockoutAccumulator) {
+		reached := make([]int, len(baseReached))
+		copy(reached, baseReached)
+		champion := simulateBracket(current, state, engine, rules, teamIndex, reached, r)
+		if champion != nil {
+			reached[teamIndex[*champion]] = state.Rounds + 1
+		}
+		for i, furthest := range reached {
+			for round := 0; round < furthest; round++ {
+				acc.reached[i][round]++
+			}
+		}
+	}
+	merge := func(total, part *knockoutAccumulator) {
+		total.merge(part)
+	}
+	stdErr := func(total *knockoutAccumulator, iterations int) float64 {
+		return total.winStdErr(iterations)
+	}
+
+	total, iterations := runMonteCarlo(opts, newAcc, iterate, merge, stdErr)
+
+	projection := &dto.KnockoutProjection{
+		LeagueID:   state.LeagueID,
+		Round:      state.Round,
+		Iterations: iterations,
+		Teams:      make([]dto.TeamKnockoutProjection, 0, len(state.Teams)),
+	}
+	for i, team := range state.Teams {
+		teamProjection := dto.TeamKnockoutProjection{
+			TeamID:             team.ID,
+			RoundProbabilities: make([]float32, state.Rounds+1),
+			WinStdErr:          float32(proportionStdErr(total.reached[i][state.Rounds], iterations)),
+		}
+		for round, count := range total.reached[i] {
+			teamProjection.RoundProbabilities[round] = float32(count) / float32(iterations)
+		}
+		projection.Teams = append(projection.Teams, teamProjection)
+	}
+	return projection, nil
+}
+
+// bracketProgress records the rounds every team already reached, whatever happens next, and
+// returns the ties of the round to be played
+func bracketProgress(state dto.KnockoutState, teamIndex map[uint]int, reached []int) []models.Tie {
+	var current []models.Tie
+	for _, tie := range state.Ties {
+		for _, teamID := range []*uint{tie.HomeTeamID, tie.AwayTeamID} {
+			if teamID != nil {
+				reached[teamIndex[*teamID]] = max(reached[teamIndex[*teamID]], tie.Round)
+			}
+		}
+		if tie.Round == state.Round {
+			current = append(current, tie)
+		}
+	}
+	return current
+}
+
+// simulateBracket plays the undecided ties of the current round and every later round,
+// recording the furthest round every team reaches, and returns the winner of the final
+func simulateBracket(current []models.Tie, state dto.KnockoutState, engine MatchEngine, rules KnockoutRules, teamIndex map[uint]int, reached []int, r *rand.Rand) *uint {
+	round := make([]models.Tie, len(current))
+	copy(round, current)
+	for {
+		for i := range round {
+			tie := &round[i]
+			for _, teamID := range []*uint{tie.HomeTeamID, tie.AwayTeamID} {
+				if teamID != nil {
+					reached[teamIndex[*teamID]] = max(reached[teamIndex[*teamID]], tie.Round)
+				}
+			}
+			if tie.WinnerID != nil {
+				continue
+			}
+			home, away := state.Teams[teamIndex[*tie.HomeTeamID]], state.Teams[teamIndex[*tie.AwayTeamID]]
+			outcome := PlayTie(engine, rules, home, away, state.TeamStats, r)
+			if outcome.HomeWins {
+				tie.WinnerID = tie.HomeTeamID
+			} else {
+				tie.WinnerID = tie.AwayTeamID
+			}
+		}
+		if len(round) <= 1 {
+			if len(round) == 0 {
+				return nil
+			}
+			return round[0].WinnerID
+		}
+		next, err := NextRoundTies(round)
+		if err != nil {
+			return nil
+		}
+		round = next
+	}
+}
@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"fmt"
+	"insider-case/app/models"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// scriptedEngine plays the legs, the extra time and the shootout it is given in order and records
+// every call with the host and guest
+type scriptedEngine struct {
+	legs      []MatchResult
+	extraTime MatchResult
+	shootout  [2]int
+	calls     []string
+}
+
+func (e *scriptedEngine) Play(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	e.calls = append(e.calls, fmt.Sprintf("play %d-%d", home.ID, away.ID))
+	result := e.legs[0]
+	e.legs = e.legs[1:]
+	return result
+}
+
+func (e *scriptedEngine) PlayExtraTime(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	e.calls = append(e.calls, fmt.Sprintf("extra time %d-%d", home.ID, away.ID))
+	result := e.extraTime
+	result.ExtraTime = true
+	return result
+}
+
+func (e *scriptedEngine) Shootout(home, away models.Team, r *rand.Rand) (int, int) {
+	e.calls = append(e.calls, fmt.Sprintf("shootout %d-%d", home.ID, away.ID))
+	return e.shootout[0], e.shootout[1]
+}
+
+func score(home, away int) MatchResult {
+	return MatchResult{HomeGoals: home, AwayGoals: away}
+}
+
+func TestPlayTie(t *testing.T) {
+	home, away := models.Team{ID: 1}, models.Team{ID: 2}
+
+	tests := []struct {
+		name   string
+		rules  KnockoutRules
+		engine *scriptedEngine
+		// expected outcome from the point of view of the tie's home team
+		homeAggregate, awayAggregate int
+		homeWins                     bool
+		decidedBy                    string
+		penalties                    *[2]int
+		calls                        []string
+	}{
+		{
+			name:          "two legs decided on aggregate",
+			rules:         KnockoutRules{Legs: 2, AwayGoals: true},
+			engine:        &scriptedEngine{legs: []MatchResult{score(2, 0), score(1, 0)}},
+			homeAggregate: 2, awayAggregate: 1,
+			homeWins:  true,
+			decidedBy: DecidedByScore,
+			calls:     []string{"play 1-2", "play 2-1"},
+		},
+		{
+			name:          "level aggregate decided by the home side's away goals",
+			rules:         KnockoutRules{Legs: 2, AwayGoals: true},
+			engine:        &scriptedEngine{legs: []MatchResult{score(1, 1), score(2, 2)}},
+			homeAggregate: 3, awayAggregate: 3,
+			homeWins:  true,
+			decidedBy: DecidedByAwayGoals,
+			calls:     []string{"play 1-2", "play 2-1"},
+		},
+		{
+			name:          "level aggregate decided by the away side's away goals",
+			rules:         KnockoutRules{Legs: 2, AwayGoals: true},
+			engine:        &scriptedEngine{legs: []MatchResult{score(2, 2), score(1, 1)}},
+			homeAggregate: 3, awayAggregate: 3,
+			homeWins:  false,
+			decidedBy: DecidedByAwayGoals,
+			calls:     []string{"play 1-2", "play 2-1"},
+		},
+		{
+			name:  "extra time when away goals are off",
+			rules: KnockoutRules{Legs: 2},
+			// the guest of the second leg, the tie's home team, scores in extra time
+			engine:        &scriptedEngine{legs: []MatchResult{score(1, 1), score(2, 2)}, extraTime: score(0, 1)},
+			homeAggregate: 4, awayAggregate: 3,
+			homeWins:  true,
+			decidedBy: DecidedByExtraTime,
+			calls:     []string{"play 1-2", "play 2-1", "extra time 2-1"},
+		},
+		{
+			name:          "shootout after level away goals and extra time",
+			rules:         KnockoutRules{Legs: 2, AwayGoals: true},
+			engine:        &scriptedEngine{legs: []MatchResult{score(1, 0), score(1, 0)}, shootout: [2]int{5, 4}},
+			homeAggregate: 1, awayAggregate: 1,
+			homeWins:  false,
+			decidedBy: DecidedByPenalties,
+			// the shootout is taken by the host of the second leg, the tie's away team
+			penalties: &[2]int{4, 5},
+			calls:     []string{"play 1-2", "play 2-1", "extra time 2-1", "shootout 2-1"},
+		},
+		{
+			name:          "one leg decided on the score",
+			rules:         KnockoutRules{Legs: 1, AwayGoals: true},
+			engine:        &scriptedEngine{legs: []MatchResult{score(0, 1)}},
+			homeAggregate: 0, awayAggregate: 1,
+			homeWins:  false,
+			decidedBy: DecidedByScore,
+			calls:     []string{"play 1-2"},
+		},
+		{
+			name:          "one leg ignores away goals and goes to extra time",
+			rules:         KnockoutRules{Legs: 1, AwayGoals: true},
+			engine:        &scriptedEngine{legs: []MatchResult{score(1, 1)}, extraTime: score(1, 0)},
+			homeAggregate: 2, awayAggregate: 1,
+			homeWins:  true,
+			decidedBy: DecidedByExtraTime,
+			calls:     []string{"play 1-2", "extra time 1-2"},
+		},
+		{
+			name:          "one leg decided by a shootout",
+			rules:         KnockoutRules{Legs: 1},
+			engine:        &scriptedEngine{legs: []MatchResult{score(0, 0)}, shootout: [2]int{3, 4}},
+			homeAggregate: 0, awayAggregate: 0,
+			homeWins:  false,
+			decidedBy: DecidedByPenalties,
+			penalties: &[2]int{3, 4},
+			calls:     []string{"play 1-2", "extra time 1-2", "shootout 1-2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := PlayTie(tt.engine, tt.rules, home, away, nil, NewRand(1))
+
+			if outcome.HomeAggregate != tt.homeAggregate || outcome.AwayAggregate != tt.awayAggregate {
+				t.Errorf("got aggregate %d-%d, want %d-%d", outcome.HomeAggregate, outcome.AwayAggregate, tt.homeAggregate, tt.awayAggregate)
+			}
+			if outcome.HomeWins != tt.homeWins || outcome.DecidedBy != tt.decidedBy {
+				t.Errorf("got home wins %t by %s, want %t by %s", outcome.HomeWins, outcome.DecidedBy, tt.homeWins, tt.decidedBy)
+			}
+			if !reflect.DeepEqual(tt.engine.calls, tt.calls) {
+				t.Errorf("got calls %v, want %v", tt.engine.calls, tt.calls)
+			}
+			if len(outcome.Legs) != tt.rules.Legs {
+				t.Fatalf("got %d legs, want %d", len(outcome.Legs), tt.rules.Legs)
+			}
+
+			last := outcome.Legs[len(outcome.Legs)-1]
+			wantExtraTime := tt.decidedBy == DecidedByExtraTime || tt.decidedBy == DecidedByPenalties
+			if last.ExtraTime != wantExtraTime {
+				t.Errorf("got extra time %t on the last leg, want %t", last.ExtraTime, wantExtraTime)
+			}
+			if tt.penalties == nil {
+				if outcome.HomePenalties != nil || last.HomePenalties != nil {
+					t.Error("got penalties without a shootout")
+				}
+				return
+			}
+			if outcome.HomePenalties == nil || outcome.AwayPenalties == nil {
+				t.Fatal("got no penalties after a shootout")
+			}
+			if got := [2]int{*outcome.HomePenalties, *outcome.AwayPenalties}; got != *tt.penalties {
+				t.Errorf("got penalties %v, want %v", got, *tt.penalties)
+			}
+			// the last leg keeps the penalties from the point of view of its host
+			lastHost, lastGuest := orientTo(tt.rules.Legs-1, MatchResult{HomeGoals: *last.HomePenalties, AwayGoals: *last.AwayPenalties})
+			if got := [2]int{lastHost, lastGuest}; got != *tt.penalties {
+				t.Errorf("got last leg penalties %d-%d, want them to orient to %v", *last.HomePenalties, *last.AwayPenalties, *tt.penalties)
+			}
+		})
+	}
+}
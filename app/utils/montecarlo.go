@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+const (
+	DefaultSimulationIterations = 10000
+	MinSimulationIterations     = 100
+	MaxSimulationIterations     = 1000000
+
+	// convergenceBatchSize is the number of iterations run between two convergence checks
+	convergenceBatchSize = 1000
+)
+
+// SimulationOptions controls a Monte Carlo run
+type SimulationOptions struct {
+	Iterations   int     // maximum number of iterations to run
+	Workers      int     // size of the worker pool, defaults to GOMAXPROCS
+	TargetStdErr float64 // stop once every probability's standard error is below it, 0 runs all iterations
+	Seed         int64   // base seed of the run, every iteration derives its own seed from it
+}
+
+// NewSimulationOptions returns the Monte Carlo options configured for the league at the given week
+func NewSimulationOptions(league models.League, week int) SimulationOptions {
+	iterations := league.SimulationIterations
+	if iterations <= 0 {
+		iterations = DefaultSimulationIterations
+	}
+	return SimulationOptions{
+		Iterations:   iterations,
+		TargetStdErr: league.TargetStdErr,
+		Seed:         DeriveSeed(league.Seed, int64(week)),
+	}
+}
+
+// runMonteCarlo runs up to opts.Iterations iterations on a bounded pool of
+// workers. Every worker collects its results in its own accumulator created by
+// newAcc, which are merged into a single accumulator once the batch is done.
+// When a target standard error is set, iterations are run in batches and the run
+// stops as soon as stdErr of the merged accumulator drops below the target.
+// It returns the merged accumulator and the number of iterations actually run.
+func runMonteCarlo[A any](
+	opts SimulationOptions,
+	newAcc func() A,
+	iterate func(r *rand.Rand, acc A),
+	merge func(total, part A),
+	stdErr func(total A, iterations int) float64,
+) (A, int) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	batchSize := opts.Iterations
+	if opts.TargetStdErr > 0 && batchSize > convergenceBatchSize {
+		batchSize = convergenceBatchSize
+	}
+
+	total := newAcc()
+	done := 0
+	for done < opts.Iterations {
+		end := min(done+batchSize, opts.Iterations)
+		partials := runBatch(done, end, workers, opts.Seed, newAcc, iterate)
+		// Merge in worker order so the result does not depend on scheduling
+		for _, part := range partials {
+			merge(total, part)
+		}
+		done = end
+
+		if opts.TargetStdErr > 0 && stdErr(total, done) < opts.TargetStdErr {
+			break
+		}
+	}
+	return total, done
+}
+
+// runBatch runs the iterations in [start, end) split into contiguous chunks, one per worker
+func runBatch[A any](start, end, workers int, seed int64, newAcc func() A, iterate func(r *rand.Rand, acc A)) []A {
+	count := end - start
+	workers = min(workers, count)
+	chunk := (count + workers - 1) / workers
+
+	partials := make([]A, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		from := start + w*chunk
+		to := min(from+chunk, end)
+		partials[w] = newAcc()
+
+		wg.Add(1)
+		go func(acc A) {
+			defer wg.Done()
+			// One generator per worker, seeded again for every iteration so the results do not
+			// depend on how the iterations are split between the workers
+			r := rand.New(&splitMixSource{})
+			for i := from; i < to; i++ {
+				r.Seed(DeriveSeed(seed, int64(i)))
+				iterate(r, acc)
+			}
+		}(partials[w])
+	}
+	wg.Wait()
+	return partials
+}
+
+// proportionStdErr is the standard error of a probability estimated from hits out of n trials
+func proportionStdErr(hits, n int) float64 {
+	if n == 0 {
+		return 1
+	}
+	p := float64(hits) / float64(n)
+	return math.Sqrt(p * (1 - p) / float64(n))
+}
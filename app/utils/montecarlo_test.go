@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestRunMonteCarloDeterministic(t *testing.T) {
+	// every iteration records the first numbers drawn from its generator
+	run := func(workers int) [][3]int64 {
+		newAcc := func() *[][3]int64 {
+			return &[][3]int64{}
+		}
+		iterate := func(r *rand.Rand, acc *[][3]int64) {
+			*acc = append(*acc, [3]int64{r.Int63(), r.Int63n(1000), int64(r.Intn(6))})
+		}
+		merge := func(total, part *[][3]int64) {
+			*total = append(*total, *part...)
+		}
+		stdErr := func(total *[][3]int64, iterations int) float64 {
+			return 0
+		}
+		total, iterations := runMonteCarlo(SimulationOptions{Iterations: 50, Workers: workers, Seed: 7}, newAcc, iterate, merge, stdErr)
+		if iterations != 50 {
+			t.Fatalf("%d workers: ran %d iterations, want 50", workers, iterations)
+		}
+		return *total
+	}
+
+	want := run(1)
+	for _, workers := range []int{2, 3, 8} {
+		if got := run(workers); !reflect.DeepEqual(got, want) {
+			t.Errorf("%d workers drew different numbers than a single worker", workers)
+		}
+	}
+
+	// an iteration draws the same numbers however many iterations its worker ran before
+	r := rand.New(&splitMixSource{})
+	r.Seed(DeriveSeed(7, 49))
+	if got := [3]int64{r.Int63(), r.Int63n(1000), int64(r.Intn(6))}; got != want[49] {
+		t.Errorf("iteration 49 drew %v, want %v", got, want[49])
+	}
+	seen := make(map[[3]int64]bool, len(want))
+	for _, draw := range want {
+		seen[draw] = true
+	}
+	if len(seen) != len(want) {
+		t.Error("iterations drew the same numbers")
+	}
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"insider-case/app/models"
+)
+
+const (
+	StagePlayoff    = "playoff"
+	StageRelegation = "relegation"
+)
+
+// NewPlayoffRules returns the knockout rules of the playoff ties
+func NewPlayoffRules(playoffs models.PlayoffSystem) KnockoutRules {
+	return KnockoutRules{
+		Legs:      max(playoffs.Legs, MinKnockoutLegs),
+		AwayGoals: playoffs.AwayGoals,
+	}
+}
+
+// HasPlayoffs reports whether the league plays any playoff after the regular season
+func HasPlayoffs(playoffs models.PlayoffSystem) bool {
+	return playoffs.Teams > 0 || playoffs.RelegationFrom > 0
+}
+
+// TitlePlayoffRounds returns the number of rounds of the title playoff, 0 when the title goes to the top team
+func TitlePlayoffRounds(playoffs models.PlayoffSystem) int {
+	return KnockoutRounds(playoffs.Teams)
+}
+
+// RelegationPlayoffRounds returns the number of rounds of the relegation playoff
+func RelegationPlayoffRounds(playoffs models.PlayoffSystem) int {
+	if playoffs.RelegationFrom == 0 {
+		return 0
+	}
+	return KnockoutRounds(playoffs.RelegationTo - playoffs.RelegationFrom + 1)
+}
+
+// PlayoffRounds returns the number of rounds of the playoffs. The title and relegation playoffs
+// are played side by side, so the longest of them sets the length of the playoff calendar.
+func PlayoffRounds(playoffs models.PlayoffSystem) int {
+	return max(TitlePlayoffRounds(playoffs), RelegationPlayoffRounds(playoffs))
+}
+
+// PlayoffWeeks returns the number of weeks played after the regular season
+func PlayoffWeeks(playoffs models.PlayoffSystem) int {
+	return PlayoffRounds(playoffs) * NewPlayoffRules(playoffs).Legs
+}
+
+// PlayoffRound returns the playoff round played in the given week of the league
+func PlayoffRound(league models.League, week int) int {
+	return KnockoutRound(week-league.MaxWeeks, NewPlayoffRules(league.Playoffs).Legs)
+}
+
+// PlayoffFirstWeek returns the week the first leg of the given playoff round is played
+func PlayoffFirstWeek(league models.League, round int) int {
+	return league.MaxWeeks + (round-1)*NewPlayoffRules(league.Playoffs).Legs + 1
+}
+
+// LeagueFinished reports whether the league played its regular season and its playoffs
+func LeagueFinished(league models.League) bool {
+	return league.CurrWeek > league.MaxWeeks+PlayoffWeeks(league.Playoffs)
+}
+
+// DrawPlayoffs seeds the first round of the playoffs from the final standings of the regular
+// season, the best placed team of every playoff being its top seed
+func DrawPlayoffs(playoffs models.PlayoffSystem, ranked []models.TeamStats) []models.Tie {
+	var ties []models.Tie
+	draw := func(stage string, standings []models.TeamStats) {
+		seeds := make([]uint, len(standings))
+		for i, stat := range standings {
+			seeds[i] = stat.TeamID
+		}
+		for _, tie := range BracketFromSeeds(seeds) {
+			tie.Stage = stage
+			ties = append(ties, tie)
+		}
+	}
+	if playoffs.Teams > 0 {
+		draw(StagePlayoff, ranked[:min(playoffs.Teams, len(ranked))])
+	}
+	if playoffs.RelegationFrom > 0 {
+		draw(StageRelegation, ranked[playoffs.RelegationFrom-1:min(playoffs.RelegationTo, len(ranked))])
+	}
+	return ties
+}
+
+// PlayoffWinner returns the winner of the final of a playoff, nil while it is not played
+func PlayoffWinner(ties []models.Tie, rounds int) *uint {
+	for _, tie := range ties {
+		if tie.Round == rounds && tie.WinnerID != nil {
+			return tie.WinnerID
+		}
+	}
+	return nil
+}
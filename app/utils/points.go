@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"math/rand"
+)
+
+const (
+	defaultWinPoints          = 3
+	defaultDrawPoints         = 1
+	defaultLossPoints         = 0
+	defaultShootoutWinPoints  = 2
+	defaultShootoutLossPoints = 1
+
+	shootoutKicks         = 5
+	penaltyConversion     = 0.75 // chance of scoring a penalty between equal teams
+	penaltyStrengthSpread = 0.1  // how far the strength share moves the conversion chance
+	maxSuddenDeathRounds  = 100
+)
+
+// DefaultPointsSystem returns the 3/1/0 points system used when a league does not configure one
+func DefaultPointsSystem() models.PointsSystem {
+	return models.PointsSystem{
+		Win:          defaultWinPoints,
+		Draw:         defaultDrawPoints,
+		Loss:         defaultLossPoints,
+		ShootoutWin:  defaultShootoutWinPoints,
+		ShootoutLoss: defaultShootoutLossPoints,
+	}
+}
+
+// DecidedByShootout reports whether a played match went to a penalty shootout
+func DecidedByShootout(match models.Match) bool {
+	return match.HomePenalties != nil && match.AwayPenalties != nil
+}
+
+// MatchWinner returns the ID of the team that won the match, including on penalties, or nil for a draw
+func MatchWinner(match models.Match) *uint {
+	switch {
+	case match.HomeScore > match.AwayScore:
+		return &match.HomeTeamID
+	case match.HomeScore < match.AwayScore:
+		return &match.AwayTeamID
+	case DecidedByShootout(match) && *match.HomePenalties > *match.AwayPenalties:
+		return &match.HomeTeamID
+	case DecidedByShootout(match):
+		return &match.AwayTeamID
+	}
+	return nil
+}
+
+// MatchPoints returns the points the home and away side get from a played match
+func MatchPoints(points models.PointsSystem, match models.Match) (int, int) {
+	homePoints, awayPoints := resultPoints(points, match)
+	homePoints += goalsBonus(points, match.HomeScore)
+	awayPoints += goalsBonus(points, match.AwayScore)
+	return homePoints, awayPoints
+}
+
+func resultPoints(points models.PointsSystem, match models.Match) (int, int) {
+	switch {
+	case match.HomeScore > match.AwayScore:
+		return points.Win, points.Loss + losingBonus(points, match.HomeScore-match.AwayScore)
+	case match.HomeScore < match.AwayScore:
+		return points.Loss + losingBonus(points, match.AwayScore-match.HomeScore), points.Win
+	case DecidedByShootout(match) && *match.HomePenalties > *match.AwayPenalties:
+		return points.ShootoutWin, points.ShootoutLoss
+	case DecidedByShootout(match):
+		return points.ShootoutLoss, points.ShootoutWin
+	}
+	return points.Draw, points.Draw
+}
+
+// goalsBonus returns the bonus points of a side scoring the given number of goals
+func goalsBonus(points models.PointsSystem, goals int) int {
+	if points.GoalsBonusThreshold > 0 && goals >= points.GoalsBonusThreshold {
+		return points.GoalsBonus
+	}
+	return 0
+}
+
+// losingBonus returns the bonus points of a side losing by the given margin
+func losingBonus(points models.PointsSystem, margin int) int {
+	if points.LosingBonusMargin > 0 && margin <= points.LosingBonusMargin {
+		return points.LosingBonus
+	}
+	return 0
+}
+
+// pointsOutcomes lists every points split a single match can end with under the points system
+func pointsOutcomes(points models.PointsSystem) []pointsOutcome {
+	var outcomes []pointsOutcome
+	seen := make(map[pointsOutcome]bool)
+	add := func(home, away int) {
+		outcome := pointsOutcome{home, away}
+		if !seen[outcome] {
+			seen[outcome] = true
+			outcomes = append(outcomes, outcome)
+		}
+	}
+
+	goalsBonuses := []int{0}
+	if points.GoalsBonusThreshold > 0 {
+		goalsBonuses = append(goalsBonuses, points.GoalsBonus)
+	}
+	losingBonuses := []int{0}
+	if points.LosingBonusMargin > 0 {
+		losingBonuses = append(losingBonuses, points.LosingBonus)
+	}
+
+	// The winner always scored at least as many goals as the loser, so the loser only
+	// gets the goals bonus along with the winner
+	for w, winnerBonus := range goalsBonuses {
+		for _, loserBonus := range goalsBonuses[:w+1] {
+			for _, losing := range losingBonuses {
+				add(points.Win+winnerBonus, points.Loss+losing+loserBonus)
+				add(points.Loss+losing+loserBonus, points.Win+winnerBonus)
+			}
+		}
+		// Level scores give both sides the same goals bonus
+		if points.NoDraws {
+			add(points.ShootoutWin+winnerBonus, points.ShootoutLoss+winnerBonus)
+			add(points.ShootoutLoss+winnerBonus, points.ShootoutWin+winnerBonus)
+		} else {
+			add(points.Draw+winnerBonus, points.Draw+winnerBonus)
+		}
+	}
+	return outcomes
+}
+
+// penaltyShootout plays a shootout of five kicks each followed by sudden death. The
+// stronger side converts its penalties slightly more often.
+func penaltyShootout(homeStrength, awayStrength int, r *rand.Rand) (int, int) {
+	homeChance, awayChance := penaltyConversion, penaltyConversion
+	if homeStrength > 0 && awayStrength > 0 {
+		homeShare := float64(homeStrength) / float64(homeStrength+awayStrength)
+		homeChance += (homeShare - 0.5) * penaltyStrengthSpread * 2
+		awayChance -= (homeShare - 0.5) * penaltyStrengthSpread * 2
+	}
+
+	homePenalties, awayPenalties := 0, 0
+	for kick := 0; kick < shootoutKicks; kick++ {
+		if r.Float64() < homeChance {
+			homePenalties++
+		}
+		if r.Float64() < awayChance {
+			awayPenalties++
+		}
+	}
+	for round := 0; homePenalties == awayPenalties; round++ {
+		homeScores := r.Float64() < homeChance
+		awayScores := r.Float64() < awayChance
+		if homeScores {
+			homePenalties++
+		}
+		if awayScores {
+			awayPenalties++
+		}
+		if round >= maxSuddenDeathRounds && homePenalties == awayPenalties {
+			homePenalties++ // practically unreachable, keeps the shootout finite
+		}
+	}
+	return homePenalties, awayPenalties
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"math"
+	"math/rand"
+)
+
+const (
+	defaultBaseGoals      = 1.3
+	defaultHomeAdvantage  = 1.2
+	defaultStrengthWeight = 1.0
+	defaultFormWeight     = 0.2
+
+	minFormFactor = 0.5
+	maxFormFactor = 2.0
+	maxGoalMean   = 10.0
+)
+
+// DefaultEngineParams returns the parameters used when a league does not override them
+func DefaultEngineParams() models.EngineParams {
+	return models.EngineParams{
+		BaseGoals:      defaultBaseGoals,
+		HomeAdvantage:  defaultHomeAdvantage,
+		StrengthWeight: defaultStrengthWeight,
+		FormWeight:     defaultFormWeight,
+	}
+}
+
+// PoissonEngine draws the goals of each side from a Poisson distribution whose
+// mean is derived from the strength ratio of the teams, home advantage and form.
+// Draws and scorelines come out of the model instead of being picked up front.
+type PoissonEngine struct {
+	params models.EngineParams
+}
+
+var _ MatchEngine = &PoissonEngine{}
+
+func (e *PoissonEngine) Play(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	homeMean, awayMean := e.goalMeans(home, away, stats)
+	homeGoals := samplePoisson(homeMean, r)
+	awayGoals := samplePoisson(awayMean, r)
+
+	return MatchResult{
+		HomeGoals: homeGoals,
+		AwayGoals: awayGoals,
+		Events:    matchEvents(home.ID, away.ID, homeGoals, awayGoals, r),
+	}
+}
+
+// PlayExtraTime draws the extra time goals from the same model, scaled to thirty minutes
+func (e *PoissonEngine) PlayExtraTime(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	homeMean, awayMean := e.goalMeans(home, away, stats)
+	scale := float64(extraTimeMinutes) / float64(matchMinutes)
+	homeGoals := samplePoisson(homeMean*scale, r)
+	awayGoals := samplePoisson(awayMean*scale, r)
+
+	return MatchResult{
+		HomeGoals: homeGoals,
+		AwayGoals: awayGoals,
+		Events:    extraTimeEvents(home.ID, away.ID, homeGoals, awayGoals, r),
+		ExtraTime: true,
+	}
+}
+
+func (e *PoissonEngine) Shootout(home, away models.Team, r *rand.Rand) (int, int) {
+	return penaltyShootout(home.Strength, away.Strength, r)
+}
+
+// goalMeans returns the expected goals of the home and away side
+func (e *PoissonEngine) goalMeans(home, away models.Team, stats []models.TeamStats) (float64, float64) {
+	ratio := 1.0
+	if home.Strength > 0 && away.Strength > 0 {
+		ratio = float64(home.Strength) / float64(away.Strength)
+	}
+
+	formFactor := CalculateFormFactor([]models.Team{home, away}, stats, home.ID)
+	formFactor = math.Max(minFormFactor, math.Min(maxFormFactor, formFactor))
+
+	strengthFactor := math.Pow(ratio, e.params.StrengthWeight)
+	form := math.Pow(formFactor, e.params.FormWeight)
+
+	homeMean := e.params.BaseGoals * e.params.HomeAdvantage * strengthFactor * form
+	awayMean := e.params.BaseGoals / (strengthFactor * form)
+
+	return math.Min(homeMean, maxGoalMean), math.Min(awayMean, maxGoalMean)
+}
+
+// samplePoisson draws a Poisson distributed value using Knuth's algorithm
+func samplePoisson(mean float64, r *rand.Rand) int {
+	if mean <= 0 {
+		return 0
+	}
+	limit := math.Exp(-mean)
+	k := 0
+	p := r.Float64()
+	for p > limit {
+		k++
+		p *= r.Float64()
+	}
+	return k
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"insider-case/app/models"
+)
+
+const (
+	DefaultSwaps = 1
+	FirstSeason  = 1
+)
+
+// DivisionSeed returns the seed of a division of the pyramid in the given season, divisions counted from 1
+func DivisionSeed(pyramid models.Pyramid, season, division int) int64 {
+	return DeriveSeed(pyramid.Seed, int64(season), int64(division))
+}
+
+// DivisionMoves returns the number of teams promoted from and relegated from the division-th
+// of divisionCount divisions, counted from 1. The top division promotes nobody and the bottom
+// one relegates nobody.
+func DivisionMoves(division, divisionCount, swaps int) (promoted, relegated int) {
+	if division > 1 {
+		promoted = swaps
+	}
+	if division < divisionCount {
+		relegated = swaps
+	}
+	return promoted, relegated
+}
+
+// RolloverDivisions returns the teams of every division next season from the final standings of
+// this season, ranked[d] holding division d's standings from the top. The promoted teams of every
+// division move up and its relegated teams move down.
+func RolloverDivisions(ranked [][]models.TeamStats, swaps int) [][]uint {
+	next := make([][]uint, len(ranked))
+	for d, standings := range ranked {
+		promoted, relegated := DivisionMoves(d+1, len(ranked), swaps)
+		for pos, stat := range standings {
+			switch {
+			case pos < promoted:
+				next[d-1] = append(next[d-1], stat.TeamID)
+			case pos >= len(standings)-relegated:
+				next[d+1] = append(next[d+1], stat.TeamID)
+			default:
+				next[d] = append(next[d], stat.TeamID)
+			}
+		}
+	}
+	return next
+}
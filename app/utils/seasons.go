@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"math"
+)
+
+const (
+	MinTeamStrength = 1000
+	MaxTeamStrength = 3000
+
+	// seasonSeedSalt separates the seed of a league's next season from the other seeds derived from the league seed
+	seasonSeedSalt = 0x5ea5
+)
+
+// CompetitionRoot returns the ID of the first season of the competition the league is a season of
+func CompetitionRoot(league models.League) uint {
+	if league.CompetitionID != nil {
+		return *league.CompetitionID
+	}
+	return league.ID
+}
+
+// NextSeasonSeed returns the seed of the season following the league
+func NextSeasonSeed(league models.League) int64 {
+	return DeriveSeed(league.Seed, seasonSeedSalt)
+}
+
+// StrengthDrift returns the strength of a team next season from its final position, counted
+// from 1. The champion gains drift, the last team loses drift and the teams in between move
+// linearly, within the strength bounds.
+func StrengthDrift(strength, position, teamCount, drift int) int {
+	if teamCount < 2 {
+		return strength
+	}
+	change := float64(drift) * float64(teamCount+1-2*position) / float64(teamCount-1)
+	return min(max(strength+int(math.Round(change)), MinTeamStrength), MaxTeamStrength)
+}
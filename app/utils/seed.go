@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"math/rand"
+	"time"
+)
+
+// maxSeed keeps generated seeds within the integer range JSON clients can represent exactly
+const maxSeed = 1<<53 - 1
+
+// GenerateSeed returns a new random seed for a league
+func GenerateSeed() int64 {
+	return time.Now().UnixNano() & maxSeed
+}
+
+// DeriveSeed deterministically derives a child seed from a base seed and a list
+// of discriminators such as the week or the iteration number.
+func DeriveSeed(base int64, parts ...int64) int64 {
+	seed := splitMix64(uint64(base))
+	for _, part := range parts {
+		seed = splitMix64(seed ^ uint64(part))
+	}
+	return int64(seed & maxSeed)
+}
+
+// NewRand returns a random number generator seeded with seed
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// splitMixSource is a rand.Source64 stepping a splitmix64 generator. Unlike the default source it
+// is cheap to seed, the Monte Carlo workers seed it again for every iteration.
+type splitMixSource struct {
+	state uint64
+}
+
+var _ rand.Source64 = &splitMixSource{}
+
+func (s *splitMixSource) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *splitMixSource) Uint64() uint64 {
+	x := splitMix64(s.state)
+	s.state += 0x9e3779b97f4a7c15
+	return x
+}
+
+func (s *splitMixSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
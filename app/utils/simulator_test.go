@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"insider-case/app/dto"
+	"insider-case/app/models"
+	"testing"
+)
+
+func TestRelegationPlayoffProjection(t *testing.T) {
+	teams := []models.Team{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}
+	stats := []models.TeamStats{{TeamID: 1, Points: 9}, {TeamID: 2, Points: 6}, {TeamID: 3, Points: 3}, {TeamID: 4, Points: 0}}
+	rules := Rules{
+		Points:      DefaultPointsSystem(),
+		TieBreakers: DefaultTieBreakers,
+		Playoffs:    models.PlayoffSystem{RelegationFrom: 3, RelegationTo: 4, Legs: 1},
+	}
+	// team 4 beats team 3 wherever they play
+	engine := &fixedEngine{results: map[[2]uint]MatchResult{
+		{3, 4}: {HomeGoals: 0, AwayGoals: 1},
+		{4, 3}: {HomeGoals: 1, AwayGoals: 0},
+	}}
+	home, away, survivor := uint(3), uint(4), uint(3)
+
+	tests := []struct {
+		name  string
+		state dto.LeagueState
+		want  []float32
+	}{
+		{
+			name: "playoff still to draw",
+			state: dto.LeagueState{
+				Week:      6,
+				Teams:     teams,
+				TeamStats: stats,
+			},
+			want: []float32{0, 0, 1, 0},
+		},
+		{
+			name: "final played",
+			state: dto.LeagueState{
+				Week:      7,
+				Teams:     teams,
+				TeamStats: stats,
+				RelegationTies: []models.Tie{
+					{Stage: StageRelegation, Round: 1, HomeTeamID: &home, AwayTeamID: &away, WinnerID: &survivor},
+				},
+				PlayoffRound: 2,
+			},
+			want: []float32{0, 0, 0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projection, err := EstimateChampionshipProbabilities(tt.state, engine, rules, SimulationOptions{Iterations: 20, Workers: 2, Seed: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, team := range projection.Teams {
+				if team.RelegationProbability == nil {
+					t.Fatalf("team %d has no relegation probability", team.TeamID)
+				}
+				if *team.RelegationProbability != tt.want[i] {
+					t.Errorf("team %d: got relegation probability %v, want %v", team.TeamID, *team.RelegationProbability, tt.want[i])
+				}
+			}
+			// the relegation playoff has no bearing on the title
+			if projection.Estimations[0].Estimation != 1 {
+				t.Errorf("team 1: got title probability %v, want 1", projection.Estimations[0].Estimation)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"sort"
+)
+
+const (
+	TieBreakGoalDifference           = "goal_difference"
+	TieBreakGoalsFor                 = "goals_for"
+	TieBreakHeadToHeadPoints         = "head_to_head_points"
+	TieBreakHeadToHeadGoalDifference = "head_to_head_goal_difference"
+	TieBreakAwayGoals                = "away_goals"
+	TieBreakWins                     = "wins"
+	TieBreakFairPlay                 = "fair_play"
+	TieBreakLots                     = "lots"
+
+	rankByPoints = "points"
+
+	// lotsSeedSalt separates the seed of the drawing of lots from the other seeds derived from the league seed
+	lotsSeedSalt = 0x1075
+
+	yellowCardFairPlayPoints = 1
+	redCardFairPlayPoints    = 3
+)
+
+// DefaultTieBreakers is the tie-breaker chain of leagues that do not configure one
+var DefaultTieBreakers = []string{TieBreakGoalDifference, TieBreakGoalsFor}
+
+// IsSupportedTieBreaker reports whether name refers to a known tie-breaker
+func IsSupportedTieBreaker(name string) bool {
+	switch name {
+	case TieBreakGoalDifference, TieBreakGoalsFor, TieBreakHeadToHeadPoints, TieBreakHeadToHeadGoalDifference,
+		TieBreakAwayGoals, TieBreakWins, TieBreakFairPlay, TieBreakLots:
+		return true
+	}
+	return false
+}
+
+// Rules are the rules of a league applied when scoring its matches and ranking its standings
+type Rules struct {
+	Points      models.PointsSystem
+	TieBreakers []string
+	LotsSeed    int64 // seed of the drawing of lots, derived from the league seed so the draw is recorded
+	Playoffs    models.PlayoffSystem
+	Promoted    int // top places moving up a division
+	Relegated   int // bottom places moving down a division
+	SwissRounds int // rounds of a Swiss-system league, 0 for other formats
+}
+
+// NewRules returns the rules configured for the league
+func NewRules(league models.League) Rules {
+	points := league.Points
+	if points == (models.PointsSystem{}) {
+		points = DefaultPointsSystem()
+	}
+	tieBreakers := league.TieBreakers
+	if len(tieBreakers) == 0 {
+		tieBreakers = DefaultTieBreakers
+	}
+	swissRounds := 0
+	if league.Format == FormatSwiss {
+		swissRounds = league.MaxWeeks
+	}
+	return Rules{
+		Points:      points,
+		TieBreakers: tieBreakers,
+		LotsSeed:    LotsSeed(league),
+		Playoffs:    league.Playoffs,
+		Promoted:    league.Promoted,
+		Relegated:   league.Relegated,
+		SwissRounds: swissRounds,
+	}
+}
+
+// LotsSeed returns the seed used to draw lots between teams of the league
+func LotsSeed(league models.League) int64 {
+	return DeriveSeed(league.Seed, lotsSeedSalt)
+}
+
+// usesMatches reports whether ranking needs the individual match results
+func (rules Rules) usesMatches() bool {
+	for _, tieBreaker := range rules.TieBreakers {
+		if tieBreaker == TieBreakHeadToHeadPoints || tieBreaker == TieBreakHeadToHeadGoalDifference {
+			return true
+		}
+	}
+	return false
+}
+
+// RankStandings returns a copy of the standings ordered by points and then by the league's
+// tie-breakers. Head-to-head criteria only count the played matches between the teams that
+// are still level, and are re-applied from the first head-to-head criterion to any smaller
+// group left level. Teams level on every criterion are ordered by ID so the ranking is
+// always deterministic.
+func RankStandings(standings []models.TeamStats, matches []models.Match, rules Rules) []models.TeamStats {
+	ranked := make([]models.TeamStats, len(standings))
+	copy(ranked, standings)
+
+	criteria := append([]string{rankByPoints}, rules.TieBreakers...)
+	rankGroup(ranked, criteria, 0, matches, rules)
+	return ranked
+}
+
+// DetermineChampion determines the champion based on final standings
+func DetermineChampion(finalStandings []models.TeamStats, matches []models.Match, rules Rules) uint {
+	return RankStandings(finalStandings, matches, rules)[0].TeamID
+}
+
+// rankGroup orders a group of teams level on every criterion before criteria[c]
+func rankGroup(group []models.TeamStats, criteria []string, c int, matches []models.Match, rules Rules) {
+	if len(group) < 2 {
+		return
+	}
+	if c == len(criteria) {
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].TeamID < group[j].TeamID
+		})
+		return
+	}
+
+	keys := tieBreakKeys(criteria[c], group, matches, rules)
+	sort.SliceStable(group, func(i, j int) bool {
+		return keys[group[i].TeamID] > keys[group[j].TeamID]
+	})
+
+	// Break the remaining ties with the next criteria
+	for start := 0; start < len(group); {
+		end := start + 1
+		for end < len(group) && keys[group[end].TeamID] == keys[group[start].TeamID] {
+			end++
+		}
+		next := c + 1
+		if isHeadToHead(criteria[c]) && end-start < len(group) {
+			// A smaller group is left level, its own head-to-head matches decide first
+			next = c
+			for next > 0 && isHeadToHead(criteria[next-1]) {
+				next--
+			}
+		}
+		rankGroup(group[start:end], criteria, next, matches, rules)
+		start = end
+	}
+}
+
+func isHeadToHead(criterion string) bool {
+	return criterion == TieBreakHeadToHeadPoints || criterion == TieBreakHeadToHeadGoalDifference
+}
+
+// tieBreakKeys returns the value of the criterion for every team of the group, higher ranks first
+func tieBreakKeys(criterion string, group []models.TeamStats, matches []models.Match, rules Rules) map[uint]int64 {
+	keys := make(map[uint]int64, len(group))
+	switch criterion {
+	case TieBreakHeadToHeadPoints, TieBreakHeadToHeadGoalDifference:
+		miniLeague := headToHeadStandings(group, matches, rules.Points)
+		for _, stat := range group {
+			if criterion == TieBreakHeadToHeadPoints {
+				keys[stat.TeamID] = int64(miniLeague[stat.TeamID].Points)
+			} else {
+				keys[stat.TeamID] = int64(miniLeague[stat.TeamID].GoalDiff)
+			}
+		}
+		return keys
+	}
+
+	for _, stat := range group {
+		switch criterion {
+		case rankByPoints:
+			keys[stat.TeamID] = int64(stat.Points)
+		case TieBreakGoalDifference:
+			keys[stat.TeamID] = int64(stat.GoalDiff)
+		case TieBreakGoalsFor:
+			keys[stat.TeamID] = int64(stat.GoalsFor)
+		case TieBreakAwayGoals:
+			keys[stat.TeamID] = int64(stat.AwayGoalsFor)
+		case TieBreakWins:
+			keys[stat.TeamID] = int64(stat.Won)
+		case TieBreakFairPlay:
+			keys[stat.TeamID] = -int64(stat.FairPlayPoints) // fewer disciplinary points ranks higher
+		case TieBreakLots:
+			keys[stat.TeamID] = DeriveSeed(rules.LotsSeed, int64(stat.TeamID))
+		}
+	}
+	return keys
+}
+
+// headToHeadStandings builds the mini-league of the played matches between the teams of the group
+func headToHeadStandings(group []models.TeamStats, matches []models.Match, points models.PointsSystem) map[uint]*models.TeamStats {
+	miniLeague := make(map[uint]*models.TeamStats, len(group))
+	for _, stat := range group {
+		miniLeague[stat.TeamID] = &models.TeamStats{TeamID: stat.TeamID}
+	}
+	for _, match := range matches {
+		home, homeOK := miniLeague[match.HomeTeamID]
+		away, awayOK := miniLeague[match.AwayTeamID]
+		if match.Played && homeOK && awayOK {
+			ApplyMatchResult(home, away, match, points)
+		}
+	}
+	return miniLeague
+}
+
+// ApplyMatchResult adds the result of a played match to the stats of both teams. A match
+// decided by a penalty shootout counts as a win and a loss.
+func ApplyMatchResult(homeStats, awayStats *models.TeamStats, match models.Match, points models.PointsSystem) {
+	homeStats.Played++
+	awayStats.Played++
+	homeStats.GoalsFor += match.HomeScore
+	awayStats.GoalsFor += match.AwayScore
+	awayStats.AwayGoalsFor += match.AwayScore
+	homeStats.GoalsAgainst += match.AwayScore
+	awayStats.GoalsAgainst += match.HomeScore
+	homeStats.GoalDiff += match.HomeScore - match.AwayScore
+	awayStats.GoalDiff += match.AwayScore - match.HomeScore
+
+	if winner := MatchWinner(match); winner == nil {
+		homeStats.Draw++
+		awayStats.Draw++
+	} else if *winner == match.HomeTeamID {
+		homeStats.Won++
+		awayStats.Lost++
+	} else {
+		homeStats.Lost++
+		awayStats.Won++
+	}
+	homePoints, awayPoints := MatchPoints(points, match)
+	homeStats.Points += homePoints
+	awayStats.Points += awayPoints
+
+	for _, event := range match.Events {
+		stats := homeStats
+		if event.TeamID == match.AwayTeamID {
+			stats = awayStats
+		}
+		switch event.Type {
+		case EventYellowCard:
+			stats.FairPlayPoints += yellowCardFairPlayPoints
+		case EventRedCard:
+			stats.FairPlayPoints += redCardFairPlayPoints
+		}
+	}
+}
+
+// ProjectStandings derives the table of the given teams from the played league matches of the
+// weeks up to and including the given week, in the order of teamIDs and with the estimations left
+// at zero. Knockout legs do not count towards the table.
+func ProjectStandings(teamIDs []uint, matches []models.Match, week int, points models.PointsSystem) []models.TeamStats {
+	stats := make([]models.TeamStats, len(teamIDs))
+	index := make(map[uint]int, len(teamIDs))
+	for i, teamID := range teamIDs {
+		stats[i].TeamID = teamID
+		index[teamID] = i
+	}
+	for _, match := range matches {
+		if !match.Played || match.TieID != nil || match.Week > week {
+			continue
+		}
+		home, homeOK := index[match.HomeTeamID]
+		away, awayOK := index[match.AwayTeamID]
+		if homeOK && awayOK {
+			ApplyMatchResult(&stats[home], &stats[away], match, points)
+		}
+	}
+	return stats
+}
+
+// SameTableRow reports whether two stats of a team agree on everything the matches decide,
+// ignoring the championship estimations
+func SameTableRow(a, b models.TeamStats) bool {
+	a.Estimation, a.EstimationIterations, a.EstimationStdErr = 0, 0, 0
+	b.Estimation, b.EstimationIterations, b.EstimationStdErr = 0, 0, 0
+	return a == b
+}
+
+// RevertMatchResult takes the result of a played match back out of the stats of its teams
+func RevertMatchResult(homeStats, awayStats *models.TeamStats, match models.Match, points models.PointsSystem) {
+	var home, away models.TeamStats
+	ApplyMatchResult(&home, &away, match, points)
+	subtractStats(homeStats, home)
+	subtractStats(awayStats, away)
+}
+
+// subtractStats removes the table columns of contribution from stats
+func subtractStats(stats *models.TeamStats, contribution models.TeamStats) {
+	stats.Points -= contribution.Points
+	stats.Played -= contribution.Played
+	stats.Won -= contribution.Won
+	stats.Lost -= contribution.Lost
+	stats.Draw -= contribution.Draw
+	stats.GoalsFor -= contribution.GoalsFor
+	stats.GoalsAgainst -= contribution.GoalsAgainst
+	stats.GoalDiff -= contribution.GoalDiff
+	stats.AwayGoalsFor -= contribution.AwayGoalsFor
+	stats.FairPlayPoints -= contribution.FairPlayPoints
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"insider-case/app/dto"
+	"insider-case/app/models"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// fixedEngine plays every match with the result given for its home and away team
+type fixedEngine struct {
+	results map[[2]uint]MatchResult
+}
+
+func (e *fixedEngine) Play(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	return e.results[[2]uint{home.ID, away.ID}]
+}
+
+func (e *fixedEngine) PlayExtraTime(home, away models.Team, stats []models.TeamStats, r *rand.Rand) MatchResult {
+	return MatchResult{ExtraTime: true}
+}
+
+func (e *fixedEngine) Shootout(home, away models.Team, r *rand.Rand) (int, int) {
+	return 5, 4
+}
+
+func played(id uint, home, away uint, homeScore, awayScore int) models.Match {
+	return models.Match{ID: id, Week: int(id), Played: true, HomeTeamID: home, AwayTeamID: away, HomeScore: homeScore, AwayScore: awayScore}
+}
+
+func teamIDs(standings []models.TeamStats) []uint {
+	ids := make([]uint, len(standings))
+	for i, stat := range standings {
+		ids[i] = stat.TeamID
+	}
+	return ids
+}
+
+// partialHeadToHead is a season where teams 1, 2 and 3 finish level on 6 points behind team 4.
+// Their mini-league puts team 2 last and leaves teams 1 and 3 level. Team 3 won two of the three
+// matches between them, while team 1 has the better goal difference in those matches and in the
+// three-way mini-league.
+func partialHeadToHead() []models.Match {
+	return []models.Match{
+		played(1, 3, 1, 1, 0),
+		played(2, 1, 3, 0, 1),
+		played(3, 1, 3, 5, 0),
+		played(4, 1, 2, 1, 0),
+		played(5, 2, 3, 1, 0),
+		played(6, 2, 4, 1, 0),
+		played(7, 4, 1, 1, 0),
+		played(8, 4, 3, 1, 0),
+		played(9, 4, 5, 1, 0),
+	}
+}
+
+var headToHeadRules = Rules{
+	Points: DefaultPointsSystem(),
+	TieBreakers: []string{
+		TieBreakHeadToHeadPoints, TieBreakHeadToHeadGoalDifference, TieBreakGoalDifference, TieBreakGoalsFor, TieBreakLots,
+	},
+	LotsSeed: 42,
+}
+
+func TestRankStandingsPartialHeadToHead(t *testing.T) {
+	matches := partialHeadToHead()
+	stats := ProjectStandings([]uint{1, 2, 3, 4, 5}, matches, len(matches), headToHeadRules.Points)
+	for _, stat := range stats[:3] {
+		if stat.Points != 6 {
+			t.Fatalf("team %d has %d points, want 6", stat.TeamID, stat.Points)
+		}
+	}
+
+	// The head-to-head criteria are applied again from the head-to-head points to teams 1 and 3
+	// alone, and team 3 has more points from their matches
+	ranked := RankStandings(stats, matches, headToHeadRules)
+	if got, want := teamIDs(ranked), []uint{4, 3, 1, 2, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ranking %v, want %v", got, want)
+	}
+}
+
+func TestRankStandingsLevelOnEveryCriterion(t *testing.T) {
+	stats := []models.TeamStats{{TeamID: 3}, {TeamID: 1}, {TeamID: 2}}
+
+	// Without lots the teams are ordered by ID
+	ranked := RankStandings(stats, nil, Rules{Points: DefaultPointsSystem(), TieBreakers: DefaultTieBreakers})
+	if got, want := teamIDs(ranked), []uint{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got ranking %v, want %v", got, want)
+	}
+
+	// Lots are drawn from the seed, the same seed always draws the same order
+	drawsDiffer := false
+	for seed := int64(1); seed <= 10; seed++ {
+		rules := Rules{Points: DefaultPointsSystem(), TieBreakers: []string{TieBreakLots}, LotsSeed: seed}
+		want := []uint{1, 2, 3}
+		sort.Slice(want, func(i, j int) bool {
+			return DeriveSeed(seed, int64(want[i])) > DeriveSeed(seed, int64(want[j]))
+		})
+		ranked := RankStandings(stats, nil, rules)
+		if got := teamIDs(ranked); !reflect.DeepEqual(got, want) {
+			t.Errorf("seed %d: got ranking %v, want %v", seed, got, want)
+		}
+		if again := teamIDs(RankStandings(stats, nil, rules)); !reflect.DeepEqual(again, want) {
+			t.Errorf("seed %d: drawing lots again gave %v, want %v", seed, again, want)
+		}
+		drawsDiffer = drawsDiffer || !reflect.DeepEqual(want, []uint{1, 2, 3})
+	}
+	if !drawsDiffer {
+		t.Error("drawing lots always ordered the teams by ID")
+	}
+
+	// The input order does not matter
+	reversed := []models.TeamStats{{TeamID: 2}, {TeamID: 1}, {TeamID: 3}}
+	rules := Rules{Points: DefaultPointsSystem(), TieBreakers: []string{TieBreakLots}, LotsSeed: 7}
+	if a, b := teamIDs(RankStandings(stats, nil, rules)), teamIDs(RankStandings(reversed, nil, rules)); !reflect.DeepEqual(a, b) {
+		t.Errorf("rankings depend on the input order: %v and %v", a, b)
+	}
+}
+
+// TestSimulatorRanksLikeStandings checks the simulated final standings are ranked exactly like the
+// standings of the same season once it is played
+func TestSimulatorRanksLikeStandings(t *testing.T) {
+	matches := partialHeadToHead()
+	teams := []models.Team{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}
+	ids := []uint{1, 2, 3, 4, 5}
+
+	// The standings endpoint ranks the stored stats with the played matches
+	standings := RankStandings(ProjectStandings(ids, matches, len(matches), headToHeadRules.Points), matches, headToHeadRules)
+
+	// The simulator plays the last three matches with the same results
+	playedSoFar, remaining := matches[:6], make([]models.Match, 0, 3)
+	engine := &fixedEngine{results: make(map[[2]uint]MatchResult)}
+	for _, match := range matches[6:] {
+		engine.results[[2]uint{match.HomeTeamID, match.AwayTeamID}] = MatchResult{HomeGoals: match.HomeScore, AwayGoals: match.AwayScore}
+		match.Played, match.HomeScore, match.AwayScore = false, 0, 0
+		remaining = append(remaining, match)
+	}
+	state := dto.LeagueState{
+		Week:             6,
+		Teams:            teams,
+		TeamStats:        ProjectStandings(ids, playedSoFar, len(matches), headToHeadRules.Points),
+		PlayedMatches:    playedSoFar,
+		RemainingMatches: remaining,
+	}
+	simulated := simulateFinalStandings(state, engine, headToHeadRules, NewRand(1))
+
+	if got, want := teamIDs(simulated), teamIDs(standings); !reflect.DeepEqual(got, want) {
+		t.Errorf("simulator ranked %v, standings ranked %v", got, want)
+	}
+	for i := range standings {
+		if !SameTableRow(simulated[i], standings[i]) {
+			t.Errorf("position %d: simulated %+v, standings %+v", i+1, simulated[i], standings[i])
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"insider-case/app/dto"
+	"insider-case/app/models"
+)
+
+const (
+	FormatSwiss = "swiss"
+
+	// swissSearchBudget bounds the number of partial pairings explored when avoiding rematches.
+	// When the budget runs out the round is paired in ranking order, rematches allowed.
+	swissSearchBudget = 100000
+)
+
+// DefaultSwissRounds returns the rounds of a Swiss-system league that does not configure them,
+// enough rounds for a single team to be left unbeaten
+func DefaultSwissRounds(teamCount int) int {
+	return max(KnockoutRounds(teamCount), 1)
+}
+
+// MaxSwissRounds returns the most rounds teamCount teams can play without a rematch
+func MaxSwissRounds(teamCount int) int {
+	return RoundsPerLeg(teamCount)
+}
+
+// SwissPairings pairs the next round of a Swiss-system league. ranked holds the team IDs ordered
+// by the current standings and previous the matches of the rounds paired so far. With an odd number
+// of teams the lowest ranked team among those with the fewest byes sits the round out. The others
+// are paired down the table, every team with the highest ranked team it has not met yet, falling
+// back to rematches when no such pairing exists. The team with fewer home games hosts, the higher
+// ranked one when both have hosted as often. Indexes of the round refer to ranked.
+func SwissPairings(ranked []uint, previous []models.Match) Round {
+	return swissPairings(ranked, previous, swissSearchBudget)
+}
+
+// swissPairings pairs the round exploring at most budget partial pairings for every bye candidate
+func swissPairings(ranked []uint, previous []models.Match, budget int) Round {
+	met := make(map[[2]uint]bool, len(previous))
+	homeGames := make(map[uint]int, len(ranked))
+	games := make(map[uint]int, len(ranked))
+	rounds := make(map[int]bool)
+	for _, match := range previous {
+		met[[2]uint{match.HomeTeamID, match.AwayTeamID}] = true
+		met[[2]uint{match.AwayTeamID, match.HomeTeamID}] = true
+		homeGames[match.HomeTeamID]++
+		games[match.HomeTeamID]++
+		games[match.AwayTeamID]++
+		rounds[match.Week] = true
+	}
+
+	// A team that played fewer games than rounds were paired had a bye. With an odd number of
+	// teams the bye goes to the lowest ranked team among those with the fewest byes that leaves
+	// a pairing without rematches.
+	candidates := []int{-1}
+	if len(ranked)%2 == 1 {
+		candidates = candidates[:0]
+		fewest := len(rounds)
+		for _, teamID := range ranked {
+			fewest = min(fewest, len(rounds)-games[teamID])
+		}
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if len(rounds)-games[ranked[i]] == fewest {
+				candidates = append(candidates, i)
+			}
+		}
+	}
+
+	partners := make([]int, len(ranked))
+	var order []int
+	left := budget
+	var search func(k int) bool
+	search = func(k int) bool {
+		for k < len(order) && partners[order[k]] >= 0 {
+			k++
+		}
+		if k == len(order) {
+			return true
+		}
+		left--
+		if left < 0 {
+			return false
+		}
+		a := order[k]
+		for _, b := range order[k+1:] {
+			if partners[b] >= 0 || met[[2]uint{ranked[a], ranked[b]}] {
+				continue
+			}
+			partners[a], partners[b] = b, a
+			if search(k + 1) {
+				return true
+			}
+			partners[a], partners[b] = -1, -1
+		}
+		return false
+	}
+	// Every bye candidate gets the whole budget, a candidate exhausting it does not rule out the next
+	pair := func(bye int) bool {
+		left = budget
+		order = order[:0]
+		for i := range ranked {
+			partners[i] = -1
+			if i != bye {
+				order = append(order, i)
+			}
+		}
+		return search(0)
+	}
+
+	bye := candidates[0]
+	paired := false
+	for _, candidate := range candidates {
+		if paired = pair(candidate); paired {
+			bye = candidate
+			break
+		}
+	}
+	if !paired {
+		pair(bye)
+		for i := range partners {
+			partners[i] = -1
+		}
+		for k := 0; k+1 < len(order); k += 2 {
+			partners[order[k]], partners[order[k+1]] = order[k+1], order[k]
+		}
+	}
+
+	var round Round
+	if bye >= 0 {
+		round.Bye = &bye
+	}
+	for _, a := range order {
+		b := partners[a]
+		if b < a {
+			continue
+		}
+		if homeGames[ranked[b]] < homeGames[ranked[a]] {
+			a, b = b, a
+		}
+		round.Pairings = append(round.Pairings, Pairing{Home: a, Away: b})
+	}
+	return round
+}
+
+// SwissRoundsToPair returns the rounds of a Swiss-system league left to pair after the rounds
+// scheduled in the league state, 0 for other formats
+func SwissRoundsToPair(leagueState dto.LeagueState, rules Rules) int {
+	if rules.SwissRounds == 0 {
+		return 0
+	}
+	return max(rules.SwissRounds-lastScheduledWeek(leagueState), 0)
+}
+
+// lastScheduledWeek returns the last week of the league state with paired matches
+func lastScheduledWeek(leagueState dto.LeagueState) int {
+	last := 0
+	for _, matches := range [][]models.Match{leagueState.PlayedMatches, leagueState.RemainingMatches} {
+		for _, match := range matches {
+			last = max(last, match.Week)
+		}
+	}
+	return last
+}
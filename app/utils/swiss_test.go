@@ -0,0 +1,150 @@
+package utils
+
+import (
+	"insider-case/app/models"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// swissRound returns the matches of a round between the given home and away team IDs
+func swissRound(week int, pairs ...[2]uint) []models.Match {
+	matches := make([]models.Match, len(pairs))
+	for i, pair := range pairs {
+		matches[i] = models.Match{Week: week, HomeTeamID: pair[0], AwayTeamID: pair[1]}
+	}
+	return matches
+}
+
+func TestSwissPairings(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranked   []uint
+		previous []models.Match
+		budget   int
+		want     []Pairing
+		bye      *int
+	}{
+		{
+			name:   "first round pairs down the table",
+			ranked: []uint{1, 2, 3, 4},
+			budget: swissSearchBudget,
+			want:   []Pairing{{Home: 0, Away: 1}, {Home: 2, Away: 3}},
+		},
+		{
+			name:     "rematches avoided",
+			ranked:   []uint{1, 2, 3, 4},
+			previous: swissRound(1, [2]uint{1, 2}, [2]uint{3, 4}),
+			budget:   swissSearchBudget,
+			// teams 2 and 4 have not hosted yet, teams 1 and 3 hosted once each so the higher ranked hosts
+			want: []Pairing{{Home: 0, Away: 2}, {Home: 1, Away: 3}},
+		},
+		{
+			name:     "bye goes to the lowest ranked team without one",
+			ranked:   []uint{1, 2, 3, 4, 5},
+			previous: swissRound(1, [2]uint{1, 2}, [2]uint{3, 4}),
+			budget:   swissSearchBudget,
+			want:     []Pairing{{Home: 0, Away: 2}, {Home: 1, Away: 4}},
+			bye:      intPtr(3),
+		},
+		{
+			name:   "rematches once every pairing is used up",
+			ranked: []uint{1, 2, 3, 4},
+			previous: append(append(
+				swissRound(1, [2]uint{1, 2}, [2]uint{3, 4}),
+				swissRound(2, [2]uint{3, 1}, [2]uint{4, 2})...),
+				swissRound(3, [2]uint{1, 4}, [2]uint{2, 3})...),
+			budget: swissSearchBudget,
+			// paired in ranking order, every team has hosted once or twice
+			want: []Pairing{{Home: 1, Away: 0}, {Home: 3, Away: 2}},
+		},
+		{
+			// without a budget for the first candidate, team 4 sits out instead of team 5
+			name:     "every bye candidate gets the whole budget",
+			ranked:   []uint{1, 2, 3, 4, 5},
+			previous: swissRound(1, [2]uint{3, 4}, [2]uint{5, 2}),
+			budget:   2,
+			want:     []Pairing{{Home: 0, Away: 1}, {Home: 2, Away: 4}},
+			bye:      intPtr(3),
+		},
+		{
+			name:     "exhausted budget pairs in ranking order",
+			ranked:   []uint{1, 2, 3, 4, 5},
+			previous: swissRound(1, [2]uint{3, 4}, [2]uint{5, 2}),
+			budget:   0,
+			want:     []Pairing{{Home: 0, Away: 1}, {Home: 3, Away: 2}},
+			bye:      intPtr(4),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			round := swissPairings(tt.ranked, tt.previous, tt.budget)
+			if !reflect.DeepEqual(round.Pairings, tt.want) {
+				t.Errorf("got pairings %v, want %v", round.Pairings, tt.want)
+			}
+			if !reflect.DeepEqual(round.Bye, tt.bye) {
+				t.Errorf("got bye %v, want %v", derefInt(round.Bye), derefInt(tt.bye))
+			}
+		})
+	}
+}
+
+// TestSwissSeason pairs whole seasons from shuffled standings and checks no team meets another
+// twice, the byes rotate and the home games stay balanced
+func TestSwissSeason(t *testing.T) {
+	for _, teamCount := range []int{6, 7, 8, 9} {
+		rounds := teamCount / 2 // few enough rounds for a rematch-free pairing to always exist
+		r := rand.New(rand.NewSource(int64(teamCount)))
+		ranked := make([]uint, teamCount)
+		for i := range ranked {
+			ranked[i] = uint(i + 1)
+		}
+
+		var previous []models.Match
+		byes := make(map[uint]int)
+		for week := 1; week <= rounds; week++ {
+			r.Shuffle(len(ranked), func(i, j int) { ranked[i], ranked[j] = ranked[j], ranked[i] })
+			round := SwissPairings(ranked, previous)
+			if round.Bye != nil {
+				byes[ranked[*round.Bye]]++
+			}
+			for _, pairing := range round.Pairings {
+				previous = append(previous, models.Match{Week: week, HomeTeamID: ranked[pairing.Home], AwayTeamID: ranked[pairing.Away]})
+			}
+		}
+
+		met := make(map[[2]uint]bool)
+		homeGames := make(map[uint]int)
+		games := make(map[uint]int)
+		for _, match := range previous {
+			pair := [2]uint{min(match.HomeTeamID, match.AwayTeamID), max(match.HomeTeamID, match.AwayTeamID)}
+			if met[pair] {
+				t.Errorf("%d teams: teams %d and %d met twice", teamCount, pair[0], pair[1])
+			}
+			met[pair] = true
+			homeGames[match.HomeTeamID]++
+			games[match.HomeTeamID]++
+			games[match.AwayTeamID]++
+		}
+		for _, teamID := range ranked {
+			if byes[teamID] > 1 {
+				t.Errorf("%d teams: team %d had %d byes", teamCount, teamID, byes[teamID])
+			}
+			if away := games[teamID] - homeGames[teamID]; homeGames[teamID]-away > 2 || away-homeGames[teamID] > 2 {
+				t.Errorf("%d teams: team %d played %d home and %d away games", teamCount, teamID, homeGames[teamID], away)
+			}
+		}
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func derefInt(i *int) any {
+	if i == nil {
+		return nil
+	}
+	return *i
+}
@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"fmt"
+	"insider-case/app/dto"
+	"insider-case/app/models"
+	"math/rand"
+	"sort"
+)
+
+const (
+	StageGroups   = "groups"
+	StageKnockout = "knockout"
+	StageFinished = "finished"
+
+	DefaultGroupLegs  = 1
+	DefaultQualifiers = 2
+
+	// knockoutSeedSalt separates the seed of a tournament's knockout stage from the seeds of its groups
+	knockoutSeedSalt = 0x6b0
+)
+
+// GroupSeed returns the seed of the group-th group of a tournament, counted from 0
+func GroupSeed(tournament models.Tournament, group int) int64 {
+	return DeriveSeed(tournament.Seed, int64(group+1))
+}
+
+// KnockoutSeed returns the seed of the knockout stage of a tournament
+func KnockoutSeed(tournament models.Tournament) int64 {
+	return DeriveSeed(tournament.Seed, knockoutSeedSalt)
+}
+
+// GroupName returns the letter name of the group-th group, counted from 0
+func GroupName(group int) string {
+	return fmt.Sprintf("Group %c", 'A'+group)
+}
+
+// DrawGroups splits the teams into groupCount groups of equal size. Seeded draws put the
+// strongest teams in the first pot and draw one team of every pot into each group, the others
+// deal out the teams shuffled with the seed.
+func DrawGroups(teams []models.Team, groupCount int, seeded bool, seed int64) [][]models.Team {
+	rng := NewRand(seed)
+	ordered := make([]models.Team, len(teams))
+	copy(ordered, teams)
+	if seeded {
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Strength > ordered[j].Strength
+		})
+		for pot := 0; pot < len(ordered); pot += groupCount {
+			potTeams := ordered[pot:min(pot+groupCount, len(ordered))]
+			rng.Shuffle(len(potTeams), func(i, j int) {
+				potTeams[i], potTeams[j] = potTeams[j], potTeams[i]
+			})
+		}
+	} else {
+		rng.Shuffle(len(ordered), func(i, j int) {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		})
+	}
+
+	groups := make([][]models.Team, groupCount)
+	for i, team := range ordered {
+		groups[i%groupCount] = append(groups[i%groupCount], team)
+	}
+	return groups
+}
+
+// GroupStageBracket seeds the knockout bracket from the qualifiers of every group, qualifiers[g]
+// holding group g's qualifiers in finishing order. The group winners are the top seeds, then come
+// the runners-up and so on, and teams of the same group are kept apart in the first round so
+// group winners meet runners-up of another group.
+func GroupStageBracket(qualifiers [][]uint) []models.Tie {
+	groupOf := make(map[uint]int)
+	var seeds []uint
+	for position := 0; ; position++ {
+		added := false
+		for group, teams := range qualifiers {
+			if position < len(teams) {
+				seeds = append(seeds, teams[position])
+				groupOf[teams[position]] = group
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+
+	ties := BracketFromSeeds(seeds)
+	sameGroup := func(home, away *uint) bool {
+		return home != nil && away != nil && groupOf[*home] == groupOf[*away]
+	}
+	for i := range ties {
+		if !sameGroup(ties[i].HomeTeamID, ties[i].AwayTeamID) {
+			continue
+		}
+		// Swap the away team with the one of the nearest tie where neither tie pairs a group with itself
+		for step := 1; step < len(ties); step++ {
+			j := (i + step) % len(ties)
+			if ties[j].AwayTeamID == nil ||
+				sameGroup(ties[i].HomeTeamID, ties[j].AwayTeamID) || sameGroup(ties[j].HomeTeamID, ties[i].AwayTeamID) {
+				continue
+			}
+			ties[i].AwayTeamID, ties[j].AwayTeamID = ties[j].AwayTeamID, ties[i].AwayTeamID
+			break
+		}
+	}
+	return ties
+}
+
+// GroupQualifiers returns the IDs of the first qualifiers teams of the ranked standings
+func GroupQualifiers(ranked []models.TeamStats, qualifiers int) []uint {
+	ids := make([]uint, 0, qualifiers)
+	for _, stat := range ranked[:min(qualifiers, len(ranked))] {
+		ids = append(ids, stat.TeamID)
+	}
+	return ids
+}
+
+// EstimateTournamentProbabilities runs Monte Carlo simulations of the rest of a tournament. Every
+// iteration completes the groups, ranks them with their tie-breakers, seeds the knockout bracket
+// from the qualifiers and plays it out, unless the knockout stage is already drawn.
+func EstimateTournamentProbabilities(state dto.TournamentState, engine MatchEngine, rules KnockoutRules, opts SimulationOptions) (*dto.TournamentProjection, error) {
+	if len(state.Groups) == 0 {
+		return nil, fmt.Errorf("no groups provided")
+	}
+	if opts.Iterations <= 0 {
+		return nil, fmt.Errorf("iteration count must be greater than 0")
+	}
+
+	// Every team of the tournament, indexed group by group
+	bracketState := dto.KnockoutState{Rounds: KnockoutRounds(len(state.Groups) * state.Qualifiers)}
+	teamGroup := make(map[uint]uint)
+	for _, group := range state.Groups {
+		for _, team := range group.Teams {
+			bracketState.Teams = append(bracketState.Teams, team)
+			teamGroup[team.ID] = group.League.ID
+		}
+	}
+	teamIndex := make(map[uint]int, len(bracketState.Teams))
+	for i, team := range bracketState.Teams {
+		teamIndex[team.ID] = i
+	}
+
+	baseReached := make([]int, len(bracketState.Teams))
+	var drawn []models.Tie
+	if state.Knockout != nil {
+		bracketState.Round = state.Knockout.Round
+		bracketState.Ties = state.Knockout.Ties
+		drawn = bracketProgress(bracketState, teamIndex, baseReached)
+	}
+	groupRules := make([]Rules, len(state.Groups))
+	for g, group := range state.Groups {
+		groupRules[g] = NewRules(group.League)
+	}
+
+	newAcc := func() *knockoutAccumulator {
+		return newKnockoutAccumulator(len(bracketState.Teams), bracketState.Rounds)
+	}
+	iterate := func(r *rand.Rand, acc *knockoutAccumulator) {
+		reached := make([]int, len(baseReached))
+		copy(reached, baseReached)
+
+		current := drawn
+		if state.Knockout == nil {
+			qualifiers := make([][]uint, len(state.Groups))
+			for g, group := range state.Groups {
+				ranked := simulateFinalStandings(group.LeagueState, engine, groupRules[g], r)
+				qualifiers[g] = GroupQualifiers(ranked, state.Qualifiers)
+			}
+			current = GroupStageBracket(qualifiers)
+		}
+
+		champion := simulateBracket(current, bracketState, engine, rules, teamIndex, reached, r)
+		if champion != nil {
+			reached[teamIndex[*champion]] = bracketState.Rounds + 1
+		}
+		for i, furthest := range reached {
+			for round := 0; round < furthest; round++ {
+				acc.reached[i][round]++
+			}
+		}
+	}
+	merge := func(total, part *knockoutAccumulator) {
+		total.merge(part)
+	}
+	stdErr := func(total *knockoutAccumulator, iterations int) float64 {
+		return total.winStdErr(iterations)
+	}
+
+	total, iterations := runMonteCarlo(opts, newAcc, iterate, merge, stdErr)
+
+	projection := &dto.TournamentProjection{
+		TournamentID: state.TournamentID,
+		Rounds:       bracketState.Rounds,
+		Iterations:   iterations,
+		Teams:        make([]dto.TeamTournamentProjection, 0, len(bracketState.Teams)),
+	}
+	for i, team := range bracketState.Teams {
+		teamProjection := dto.TeamTournamentProjection{
+			TeamID:             team.ID,
+			GroupID:            teamGroup[team.ID],
+			RoundProbabilities: make([]float32, bracketState.Rounds+1),
+			WinStdErr:          float32(proportionStdErr(total.reached[i][bracketState.Rounds], iterations)),
+		}
+		for round, count := range total.reached[i] {
+			teamProjection.RoundProbabilities[round] = float32(count) / float32(iterations)
+		}
+		projection.Teams = append(projection.Teams, teamProjection)
+	}
+	return projection, nil
+}